@@ -0,0 +1,160 @@
+package manager
+
+import (
+	"orion/server/data/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// maxActivePosters – количество самых активных авторов в отчёте аналитики.
+const maxActivePosters = 10
+
+// DayCount – значение показателя за один день (UTC).
+type DayCount struct {
+	Date  string `json:"date"`
+	Count int    `json:"count"`
+}
+
+// HourCount – количество сообщений, отправленных в указанный час суток (UTC).
+type HourCount struct {
+	Hour  int `json:"hour"`
+	Count int `json:"count"`
+}
+
+// PosterStat – активность автора сообщений.
+type PosterStat struct {
+	UserID   uint   `json:"userId"`
+	UserName string `json:"username"`
+	Messages int    `json:"messages"`
+}
+
+// ReadRate – доля прочитанных сообщений.
+type ReadRate struct {
+	Messages int     `json:"messages"`
+	Read     int     `json:"read"`
+	Rate     float64 `json:"rate"`
+}
+
+// ChannelAnalytics – отчёт по активности канала за период [From, To).
+type ChannelAnalytics struct {
+	ChatID         uint         `json:"chatId"`
+	From           time.Time    `json:"from"`
+	To             time.Time    `json:"to"`
+	TotalMessages  int          `json:"totalMessages"`
+	TotalPosters   int          `json:"totalPosters"`
+	Members        int          `json:"members"`
+	MessagesPerDay []DayCount   `json:"messagesPerDay"`
+	ActivePosters  []PosterStat `json:"activePosters"`
+	PeakHours      []HourCount  `json:"peakHours"`
+	MemberJoins    []DayCount   `json:"memberJoins"`
+	MemberLeaves   []DayCount   `json:"memberLeaves"`
+	MemberGrowth   []DayCount   `json:"memberGrowth"` // Чистый прирост: вступления минус выходы
+	ReadRate       ReadRate     `json:"readRate"`
+}
+
+// GetChannelAnalytics рассчитывает аналитику канала за период [from, to).
+// Учитываются только пользовательские сообщения: служебные и удалённые пропускаются.
+//
+// Параметры:
+//   - chatID: идентификатор канала.
+//   - from, to: границы периода (UTC).
+//
+// Возвращаемые значения:
+//   - ChannelAnalytics: отчёт; дни и часы без активности в рядах отсутствуют.
+//   - error: ошибка запроса к базе данных.
+func GetChannelAnalytics(chatID uint, from, to time.Time) (ChannelAnalytics, error) {
+	report := ChannelAnalytics{
+		ChatID:         chatID,
+		From:           from,
+		To:             to,
+		MessagesPerDay: []DayCount{},
+		ActivePosters:  []PosterStat{},
+		PeakHours:      []HourCount{},
+		MemberJoins:    []DayCount{},
+		MemberLeaves:   []DayCount{},
+		MemberGrowth:   []DayCount{},
+	}
+	messages := func() *gorm.DB {
+		return DB.Model(&models.Message{}).
+			Where("messages.channel_id = ? AND messages.system = false", chatID).
+			Where("messages.timestamp >= ? AND messages.timestamp < ?", from, to)
+	}
+
+	var totals struct {
+		Messages int
+		Posters  int
+		Read     int
+	}
+	err := messages().
+		Select(`COUNT(*) AS messages, COUNT(DISTINCT messages.user_id) AS posters,
+			COUNT(*) FILTER (WHERE EXISTS (SELECT 1 FROM read_cursors
+				WHERE read_cursors.channel_id = messages.channel_id AND read_cursors.user_id != messages.user_id
+				  AND read_cursors.last_read_id >= messages.id)) AS read`).
+		Scan(&totals).Error
+	if err != nil {
+		return report, err
+	}
+	report.TotalMessages = totals.Messages
+	report.TotalPosters = totals.Posters
+	report.ReadRate = ReadRate{Messages: totals.Messages, Read: totals.Read}
+	if totals.Messages > 0 {
+		report.ReadRate.Rate = float64(totals.Read) / float64(totals.Messages)
+	}
+	report.Members = SubscriberCount(chatID)
+
+	err = messages().
+		Select("to_char(date_trunc('day', messages.timestamp AT TIME ZONE 'UTC'), 'YYYY-MM-DD') AS date, COUNT(*) AS count").
+		Group("date").
+		Order("date").
+		Scan(&report.MessagesPerDay).Error
+	if err != nil {
+		return report, err
+	}
+
+	err = messages().
+		Select("messages.user_id, users.user_name, COUNT(*) AS messages").
+		Joins("JOIN users ON users.id = messages.user_id").
+		Group("messages.user_id, users.user_name").
+		Order("messages DESC, messages.user_id").
+		Limit(maxActivePosters).
+		Scan(&report.ActivePosters).Error
+	if err != nil {
+		return report, err
+	}
+
+	err = messages().
+		Select("EXTRACT(HOUR FROM messages.timestamp AT TIME ZONE 'UTC')::int AS hour, COUNT(*) AS count").
+		Group("hour").
+		Order("count DESC, hour").
+		Scan(&report.PeakHours).Error
+	if err != nil {
+		return report, err
+	}
+
+	var members []struct {
+		Date   string
+		Joins  int
+		Leaves int
+	}
+	err = DB.Model(&models.MemberEvent{}).
+		Select(`to_char(date_trunc('day', created_at AT TIME ZONE 'UTC'), 'YYYY-MM-DD') AS date,
+			COUNT(*) FILTER (WHERE joined) AS joins, COUNT(*) FILTER (WHERE NOT joined) AS leaves`).
+		Where("channel_id = ? AND created_at >= ? AND created_at < ?", chatID, from, to).
+		Group("date").
+		Order("date").
+		Scan(&members).Error
+	if err != nil {
+		return report, err
+	}
+	for _, day := range members {
+		if day.Joins > 0 {
+			report.MemberJoins = append(report.MemberJoins, DayCount{Date: day.Date, Count: day.Joins})
+		}
+		if day.Leaves > 0 {
+			report.MemberLeaves = append(report.MemberLeaves, DayCount{Date: day.Date, Count: day.Leaves})
+		}
+		report.MemberGrowth = append(report.MemberGrowth, DayCount{Date: day.Date, Count: day.Joins - day.Leaves})
+	}
+	return report, nil
+}
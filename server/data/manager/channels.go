@@ -0,0 +1,123 @@
+package manager
+
+import (
+	"orion/server/data/models"
+	"strings"
+	"time"
+)
+
+// ChannelSummary – публичный канал в каталоге.
+//
+// Поля структуры:
+//   - ID, Title, Description: идентификатор, название и описание канала.
+//   - Subscribers: количество подписчиков (участников канала).
+//   - Subscribed: подписан ли пользователь, выполнявший запрос.
+type ChannelSummary struct {
+	ID          uint   `json:"id"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Subscribers int    `json:"subscribers"`
+	Subscribed  bool   `json:"subscribed"`
+}
+
+// IsPublicChannel проверяет, является ли канал публичным.
+func IsPublicChannel(chatID uint) bool {
+	chat := GetChatByID(chatID)
+	return chat.ID != 0 && chat.IsGroup && !chat.IsPrivate
+}
+
+// ListPublicChannels ищет публичные каналы по названию и описанию.
+//
+// Параметры:
+//   - query: подстрока для поиска (пустая строка – все каналы).
+//   - userID: пользователь, для которого заполняется признак Subscribed.
+//   - limit, offset: параметры постраничной выдачи.
+//
+// Возвращаемые значения:
+//   - []ChannelSummary: каналы, упорядоченные по убыванию числа подписчиков.
+//   - error: ошибка запроса к базе данных.
+func ListPublicChannels(query string, userID uint, limit, offset int) ([]ChannelSummary, error) {
+	q := DB.Model(&models.Channel{}).
+		Select(`channels.id, channels.title, channels.description,
+			(SELECT COUNT(*) FROM user_channels WHERE user_channels.channel_id = channels.id) AS subscribers,
+			EXISTS (SELECT 1 FROM user_channels WHERE user_channels.channel_id = channels.id AND user_channels.user_id = ?) AS subscribed`, userID).
+		Where("channels.is_group = true AND channels.is_private = false")
+	if query = strings.TrimSpace(query); query != "" {
+		pattern := "%" + escapeLike(query) + "%"
+		q = q.Where("channels.title ILIKE ? OR channels.description ILIKE ?", pattern, pattern)
+	}
+
+	channels := []ChannelSummary{}
+	err := q.Order("subscribers DESC, channels.id").
+		Limit(limit).
+		Offset(offset).
+		Scan(&channels).Error
+	return channels, err
+}
+
+// SubscriberCount возвращает количество участников канала.
+func SubscriberCount(chatID uint) int {
+	var count int64
+	DB.Table("user_channels").Where("channel_id = ?", chatID).Count(&count)
+	return int(count)
+}
+
+// GetRecentMessages возвращает последние limit сообщений канала в порядке отправки.
+func GetRecentMessages(chatID uint, limit int) ([]models.Message, error) {
+	var msgs []models.Message
+	err := DB.Where("channel_id = ?", chatID).
+		Order("id DESC").
+		Limit(limit).
+		Find(&msgs).Error
+	if err != nil {
+		return nil, err
+	}
+	for i, j := 0, len(msgs)-1; i < j; i, j = i+1, j-1 {
+		msgs[i], msgs[j] = msgs[j], msgs[i]
+	}
+	return msgs, nil
+}
+
+// escapeLike экранирует спецсимволы шаблона LIKE.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// UpdateChannelSettings изменяет название, описание и аватар канала. Поля со значением nil не меняются.
+//
+// Возвращаемые значения:
+//   - Channel: канал после изменения.
+//   - error: ошибка записи в базу данных.
+func UpdateChannelSettings(chatID uint, title, description, avatar *string) (models.Channel, error) {
+	updates := map[string]interface{}{}
+	if title != nil {
+		updates["title"] = *title
+	}
+	if description != nil {
+		updates["description"] = *description
+	}
+	if avatar != nil {
+		updates["avatar"] = *avatar
+	}
+	if len(updates) > 0 {
+		if err := DB.Model(&models.Channel{ID: chatID}).Updates(updates).Error; err != nil {
+			return models.Channel{}, err
+		}
+	}
+	return GetChatByID(chatID), nil
+}
+
+// AddSystemMessage сохраняет служебное сообщение в канале от имени пользователя userID.
+func AddSystemMessage(chatID, userID uint, content string) (models.Message, error) {
+	mess := models.Message{
+		ChannelID: chatID,
+		UserID:    userID,
+		Content:   content,
+		Timestamp: time.Now(),
+		System:    true,
+	}
+	if err := insertMessage(&mess); err != nil {
+		return models.Message{}, err
+	}
+	return mess, nil
+}
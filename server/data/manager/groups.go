@@ -0,0 +1,180 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"orion/server/data/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrNotGroup возвращается при попытке изменить состав личного чата.
+var ErrNotGroup = errors.New("chat is not a group")
+
+// IsDirectChat проверяет, является ли канал личным чатом двух пользователей.
+func IsDirectChat(chatID uint) bool {
+	var chat models.Channel
+	if err := DB.First(&chat, chatID).Error; err != nil || chat.IsGroup {
+		return false
+	}
+	return DB.Model(&chat).Association("Users").Count() == 2
+}
+
+// CreateGroup создаёт групповой чат или публичный канал и добавляет в него создателя и участников memberIDs.
+//
+// Параметры:
+//   - creatorID: идентификатор создателя группы.
+//   - title: отображаемое название группы.
+//   - description: описание группы.
+//   - memberIDs: идентификаторы приглашённых участников (создатель добавляется автоматически).
+//   - public: true – публичный канал, на который может подписаться любой пользователь.
+//
+// Возвращаемые значения:
+//   - *Channel: созданная группа вместе с участниками.
+//   - error: ошибка, если кто-то из участников не найден или группу не удалось сохранить.
+func CreateGroup(creatorID uint, title, description string, memberIDs []uint, public bool) (*models.Channel, error) {
+	ids := append([]uint{creatorID}, memberIDs...)
+	var users []models.User
+	if err := DB.Where("id IN ?", ids).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	if len(users) != len(uniqueIDs(ids)) {
+		return nil, fmt.Errorf("%w: some members do not exist", gorm.ErrRecordNotFound)
+	}
+
+	prefix := "group"
+	if public {
+		prefix = "channel"
+	}
+	chat := models.Channel{
+		Name:        fmt.Sprintf("%s_%d_%d", prefix, creatorID, time.Now().UnixNano()),
+		Title:       title,
+		Description: description,
+		IsPrivate:   !public,
+		IsGroup:     true,
+		CreatorID:   creatorID,
+		Users:       users,
+	}
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&chat).Error; err != nil {
+			return err
+		}
+		if err := createDefaultRoles(tx, chat.ID, creatorID, uniqueIDs(ids), public); err != nil {
+			return err
+		}
+		return recordMemberEvents(tx, chat.ID, uniqueIDs(ids), true)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create group: %w", err)
+	}
+	return &chat, nil
+}
+
+// AddChatMembers добавляет пользователей в групповой чат с ролью member.
+// Уже состоящие в нём пользователи пропускаются.
+//
+// Возвращаемые значения:
+//   - []uint: идентификаторы действительно добавленных пользователей.
+//   - error: ErrNotGroup для личного чата, ошибка базы данных в остальных случаях.
+func AddChatMembers(chatID uint, userIDs []uint) ([]uint, error) {
+	chat := GetChatByID(chatID)
+	if chat.ID == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	if !chat.IsGroup {
+		return nil, ErrNotGroup
+	}
+
+	var users []models.User
+	err := DB.Where("id IN ?", uniqueIDs(userIDs)).
+		Where("id NOT IN (?)", DB.Table("user_channels").Select("user_id").Where("channel_id = ?", chatID)).
+		Find(&users).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(users) == 0 {
+		return []uint{}, nil
+	}
+	member, err := GetRoleByBuiltin(chatID, RoleMember)
+	if err != nil {
+		return nil, err
+	}
+
+	added := make([]uint, 0, len(users))
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&chat).Association("Users").Append(&users); err != nil {
+			return err
+		}
+		for _, u := range users {
+			if err := assignRole(tx, u.ID, member.ID); err != nil {
+				return err
+			}
+			added = append(added, u.ID)
+		}
+		return recordMemberEvents(tx, chatID, added, true)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return added, nil
+}
+
+// RemoveChatMember исключает пользователя из группового чата.
+//
+// Возвращаемые значения:
+//   - bool: true, если пользователь состоял в группе и был исключён.
+//   - error: ErrNotGroup для личного чата, ошибка базы данных в остальных случаях.
+func RemoveChatMember(chatID, userID uint) (bool, error) {
+	chat := GetChatByID(chatID)
+	if chat.ID == 0 {
+		return false, gorm.ErrRecordNotFound
+	}
+	if !chat.IsGroup {
+		return false, ErrNotGroup
+	}
+	var removed bool
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		res := tx.Exec("DELETE FROM user_channels WHERE channel_id = ? AND user_id = ?", chatID, userID)
+		if res.Error != nil {
+			return res.Error
+		}
+		removed = res.RowsAffected > 0
+		if removed {
+			if err := recordMemberEvents(tx, chatID, []uint{userID}, false); err != nil {
+				return err
+			}
+		}
+		// Роли исключённого пользователя в этом канале снимаются
+		return tx.Exec("DELETE FROM user_statuses WHERE user_id = ? AND status_id IN (SELECT id FROM statuses WHERE channel_id = ?)",
+			userID, chatID).Error
+	})
+	return removed, err
+}
+
+// recordMemberEvents записывает в журнал вступление (joined = true) или выход пользователей userIDs.
+func recordMemberEvents(tx *gorm.DB, chatID uint, userIDs []uint, joined bool) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	now := time.Now()
+	events := make([]models.MemberEvent, 0, len(userIDs))
+	for _, id := range userIDs {
+		events = append(events, models.MemberEvent{ChannelID: chatID, UserID: id, Joined: joined, CreatedAt: now})
+	}
+	return tx.Create(&events).Error
+}
+
+// uniqueIDs возвращает идентификаторы без повторов и нулей, сохраняя порядок.
+func uniqueIDs(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	result := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if id == 0 || seen[id] {
+			continue
+		}
+		seen[id] = true
+		result = append(result, id)
+	}
+	return result
+}
@@ -0,0 +1,204 @@
+package manager
+
+import (
+	"orion/server/data/models"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GetMessageByID возвращает сообщение по его ID.
+//
+// Возвращаемые значения:
+//   - Message: найденное сообщение.
+//   - error: gorm.ErrRecordNotFound, если сообщение не найдено или удалено.
+func GetMessageByID(messageID uint) (models.Message, error) {
+	var msg models.Message
+	err := DB.First(&msg, messageID).Error
+	return msg, err
+}
+
+// EditMessage заменяет текст сообщения, сохраняя предыдущую версию в истории правок.
+//
+// Параметры:
+//   - messageID: идентификатор сообщения.
+//   - editorID: идентификатор пользователя, выполняющего правку.
+//   - content: новый текст сообщения.
+//
+// Возвращаемые значения:
+//   - Message: обновлённое сообщение.
+//   - bool: false, если текст не изменился и правка не сохранялась.
+//   - error: ошибка, если сообщение не найдено или не удалось сохранить изменения.
+func EditMessage(messageID, editorID uint, content string) (models.Message, bool, error) {
+	var msg models.Message
+	changed := false
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&msg, messageID).Error; err != nil {
+			return err
+		}
+		if msg.Content == content {
+			return nil
+		}
+
+		edit := models.MessageEdit{
+			MessageID:       msg.ID,
+			EditorID:        editorID,
+			PreviousContent: msg.Content,
+			EditedAt:        time.Now(),
+		}
+		if err := tx.Create(&edit).Error; err != nil {
+			return err
+		}
+
+		msg.Content = content
+		msg.Edited = true
+		changed = true
+		return tx.Model(&msg).Updates(map[string]interface{}{"content": content, "edited": true}).Error
+	})
+	return msg, changed, err
+}
+
+// GetMessageEdits возвращает историю правок сообщения, от старых к новым.
+func GetMessageEdits(messageID uint) ([]models.MessageEdit, error) {
+	var edits []models.MessageEdit
+	err := DB.Where("message_id = ?", messageID).Order("id").Find(&edits).Error
+	return edits, err
+}
+
+// DeleteMessageForAll помечает сообщение удалённым для всех участников чата (мягкое удаление).
+// Запись остаётся в базе как «надгробие», но больше не возвращается в истории чата;
+// закрепление сообщения снимается.
+func DeleteMessageForAll(messageID uint) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("message_id = ?", messageID).Delete(&models.PinnedMessage{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Message{}, messageID).Error
+	})
+}
+
+// HideMessage скрывает сообщение только для пользователя userID. Повторный вызов не является ошибкой.
+func HideMessage(userID, messageID uint) error {
+	hidden := models.HiddenMessage{UserID: userID, MessageID: messageID}
+	return DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&hidden).Error
+}
+
+// GetHiddenMessageIDs возвращает те из сообщений ids, которые пользователь userID скрыл у себя.
+func GetHiddenMessageIDs(userID uint, ids []uint) (map[uint]bool, error) {
+	result := make(map[uint]bool)
+	if len(ids) == 0 {
+		return result, nil
+	}
+	var hidden []uint
+	err := DB.Model(&models.HiddenMessage{}).
+		Where("user_id = ? AND message_id IN ?", userID, ids).
+		Pluck("message_id", &hidden).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range hidden {
+		result[id] = true
+	}
+	return result, nil
+}
+
+// GetMessagesByIDs возвращает сообщения по списку ID, включая удалённые для всех
+// (у них заполнено DeletedAt). Используется для цитат родительских сообщений.
+func GetMessagesByIDs(ids []uint) (map[uint]models.Message, error) {
+	result := make(map[uint]models.Message, len(ids))
+	if len(ids) == 0 {
+		return result, nil
+	}
+	var msgs []models.Message
+	if err := DB.Unscoped().Where("id IN ?", ids).Find(&msgs).Error; err != nil {
+		return nil, err
+	}
+	for _, m := range msgs {
+		result[m.ID] = m
+	}
+	return result, nil
+}
+
+// GetReplyCounts возвращает количество ответов на каждое из сообщений, видимых пользователю userID:
+// удалённые для всех и скрытые им ответы не учитываются. Сообщения без ответов в результат не попадают.
+func GetReplyCounts(ids []uint, userID uint) (map[uint]int, error) {
+	result := make(map[uint]int)
+	if len(ids) == 0 {
+		return result, nil
+	}
+	var rows []struct {
+		ReplyToID uint
+		Count     int
+	}
+	err := DB.Model(&models.Message{}).
+		Select("reply_to_id, COUNT(*) AS count").
+		Where("reply_to_id IN ?", ids).
+		Where(notHiddenFor, userID).
+		Group("reply_to_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		result[row.ReplyToID] = row.Count
+	}
+	return result, nil
+}
+
+// GetThread возвращает ответы на сообщение rootID в порядке отправки.
+// Сообщения, скрытые пользователем userID, не возвращаются.
+func GetThread(rootID, userID uint) ([]models.Message, error) {
+	var replies []models.Message
+	err := DB.Where("reply_to_id = ?", rootID).
+		Where(notHiddenFor, userID).
+		Order("id").
+		Find(&replies).Error
+	return replies, err
+}
+
+// ForwardMessages сохраняет копии сообщений originals в каждом канале chatIDs от имени пользователя userID
+// в одной транзакции: при ошибке не сохраняется ни одна копия.
+// Копия ссылается на автора и канал оригинала; при пересылке уже пересланного сообщения
+// сохраняется ссылка на самый первый источник.
+//
+// Каналы обрабатываются по возрастанию ID, чтобы параллельные пересылки блокировали строки
+// каналов в одном порядке; внутри канала копии идут в порядке originals.
+//
+// Возвращаемые значения:
+//   - map[uint][]Message: сохранённые копии по каналам.
+//   - error: ошибка записи; в этом случае ничего не сохранено.
+func ForwardMessages(userID uint, chatIDs []uint, originals []models.Message) (map[uint][]models.Message, error) {
+	chats := uniqueIDs(chatIDs)
+	sort.Slice(chats, func(i, j int) bool { return chats[i] < chats[j] })
+
+	stored := make(map[uint][]models.Message, len(chats))
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		for _, chatID := range chats {
+			for _, original := range originals {
+				fromUserID, fromChatID := original.UserID, original.ChannelID
+				if original.ForwardedFromUserID != nil && original.ForwardedFromChatID != nil {
+					fromUserID, fromChatID = *original.ForwardedFromUserID, *original.ForwardedFromChatID
+				}
+				mess := models.Message{
+					ChannelID:           chatID,
+					UserID:              userID,
+					Content:             original.Content,
+					Timestamp:           time.Now(),
+					ForwardedFromUserID: &fromUserID,
+					ForwardedFromChatID: &fromChatID,
+				}
+				if err := insertMessageTx(tx, &mess); err != nil {
+					return err
+				}
+				stored[chatID] = append(stored[chatID], mess)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return stored, nil
+}
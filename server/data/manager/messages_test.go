@@ -0,0 +1,30 @@
+//go:build integration
+
+package manager
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAddMessageClientMsgID(t *testing.T) {
+	owner := newTestUser(t)
+	first := newTestGroup(t, owner, nil, false)
+	second := newTestGroup(t, owner, nil, false)
+
+	msg, duplicate, err := AddMessage(owner, first, "hello", "client-1", 0)
+	if err != nil || duplicate {
+		t.Fatalf("first send: duplicate = %v, err = %v", duplicate, err)
+	}
+	if _, _, err := AddMessage(owner, second, "hello", "client-1", 0); !errors.Is(err, ErrClientMsgIDConflict) {
+		t.Errorf("reuse in another chat: err = %v, want ErrClientMsgIDConflict", err)
+	}
+
+	if err := DeleteMessageForAll(msg.ID); err != nil {
+		t.Fatal(err)
+	}
+	retry, duplicate, err := AddMessage(owner, first, "hello", "client-1", 0)
+	if err != nil || !duplicate || retry.ID != msg.ID {
+		t.Errorf("retry after delete: id = %d, duplicate = %v, err = %v; want id %d duplicate", retry.ID, duplicate, err, msg.ID)
+	}
+}
@@ -1,10 +1,87 @@
 package manager
 
 import (
+	"log"
 	"orion/server/data/models"
 )
 
+// indexes – индексы и колонки, которые нельзя описать тегами gorm (составные индексы, колонки join-таблиц).
+var indexes = []string{
+	// Клиентский ID сообщения уникален в пределах отправителя; NULL допускается многократно.
+	`CREATE UNIQUE INDEX IF NOT EXISTS idx_messages_sender_client_msg ON messages (user_id, client_msg_id)`,
+	`CREATE INDEX IF NOT EXISTS idx_messages_channel_seq ON messages (channel_id, seq)`,
+	// Постраничная загрузка истории чата по курсорам before/after.
+	`CREATE INDEX IF NOT EXISTS idx_messages_channel_id ON messages (channel_id, id)`,
+	`CREATE INDEX IF NOT EXISTS idx_messages_channel_timestamp ON messages (channel_id, timestamp)`,
+	// Полнотекстовый поиск по сообщениям; выражение совпадает с messageSearchVector.
+	`CREATE INDEX IF NOT EXISTS idx_messages_content_search ON messages USING GIN ((to_tsvector('russian', content) || to_tsvector('english', content)))`,
+	// Нечёткий поиск пользователей по имени: триграммы ускоряют и ILIKE '%…%', и оператор %.
+	`CREATE EXTENSION IF NOT EXISTS pg_trgm`,
+	`CREATE INDEX IF NOT EXISTS idx_users_user_name_trgm ON users USING GIN (user_name gin_trgm_ops)`,
+	`CREATE INDEX IF NOT EXISTS idx_users_display_name_trgm ON users USING GIN (display_name gin_trgm_ops)`,
+}
+
 func Migrate() {
 
-	DB.AutoMigrate(&models.User{}, &models.Message{}, &models.Channel{}, &models.Status{})
+	DB.AutoMigrate(&models.User{}, &models.Message{}, &models.Channel{}, &models.Status{}, &models.Presence{},
+		&models.MessageEdit{}, &models.HiddenMessage{},
+		&models.Reaction{}, &models.PinnedMessage{}, &models.ReadCursor{}, &models.MemberEvent{})
+
+	backfillMessageSeq()
+	backfillReadCursors()
+	backfillMemberEvents()
+	if err := backfillDefaultRoles(); err != nil {
+		log.Printf("Migrate: backfill roles: %v", err)
+	}
+	for _, ddl := range indexes {
+		if err := DB.Exec(ddl).Error; err != nil {
+			log.Printf("Migrate: %v", err)
+		}
+	}
+}
+
+// backfillMessageSeq нумерует сообщения, созданные до появления поля seq,
+// и выставляет каналам last_seq по максимальному номеру.
+func backfillMessageSeq() {
+	res := DB.Exec(`
+		UPDATE messages SET seq = numbered.rn
+		FROM (
+			SELECT id, ROW_NUMBER() OVER (PARTITION BY channel_id ORDER BY id) AS rn
+			FROM messages
+		) AS numbered
+		WHERE messages.id = numbered.id
+		  AND EXISTS (SELECT 1 FROM messages m WHERE m.channel_id = messages.channel_id AND m.seq = 0)`)
+	if res.Error != nil {
+		log.Printf("Migrate: backfill seq: %v", res.Error)
+		return
+	}
+	if res.RowsAffected == 0 {
+		return
+	}
+	if err := DB.Exec(`
+		UPDATE channels SET last_seq = COALESCE(
+			(SELECT MAX(seq) FROM messages WHERE messages.channel_id = channels.id), 0)`).Error; err != nil {
+		log.Printf("Migrate: backfill last_seq: %v", err)
+	}
+}
+
+// backfillMemberEvents создаёт события вступления для участников групп, вступивших до появления
+// журнала member_events. Точное время вступления неизвестно: для создателя берётся время создания
+// канала, для остальных – время их первого сообщения в канале, а при его отсутствии – время создания канала.
+func backfillMemberEvents() {
+	if err := DB.Exec(`
+		INSERT INTO member_events (channel_id, user_id, joined, created_at)
+		SELECT user_channels.channel_id, user_channels.user_id, true,
+			CASE WHEN user_channels.user_id = channels.creator_id THEN channels.created_at
+				ELSE COALESCE((SELECT MIN(messages.timestamp) FROM messages
+					WHERE messages.channel_id = user_channels.channel_id AND messages.user_id = user_channels.user_id),
+					channels.created_at)
+			END
+		FROM user_channels
+		JOIN channels ON channels.id = user_channels.channel_id
+		WHERE channels.is_group = true
+		  AND NOT EXISTS (SELECT 1 FROM member_events
+			WHERE member_events.channel_id = user_channels.channel_id AND member_events.user_id = user_channels.user_id)`).Error; err != nil {
+		log.Printf("Migrate: backfill member events: %v", err)
+	}
 }
@@ -0,0 +1,482 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"orion/server/data/models"
+
+	"gorm.io/gorm"
+)
+
+// Имена привилегий совпадают с колонками таблицы statuses.
+const (
+	PrivEditing          = "editing_priv"
+	PrivDeletion         = "deletion_priv"
+	PrivWriting          = "writing_priv"
+	PrivAdmin            = "admin_priv"
+	PrivManagingUsers    = "managing_users_priv"
+	PrivPinningMessages  = "pinning_messages_priv"
+	PrivReading          = "reading_priv"
+	PrivInviting         = "inviting_priv"
+	PrivViewingAnalytics = "viewing_analytics_priv"
+	PrivChannelEdit      = "channel_edit_priv"
+)
+
+// Встроенные роли, создаваемые вместе с группой.
+const (
+	RoleOwner  = "owner"
+	RoleAdmin  = "admin"
+	RoleMember = "member"
+)
+
+var (
+	// ErrBuiltinRole возвращается при попытке удалить встроенную роль или изменить роль owner.
+	ErrBuiltinRole = errors.New("builtin role cannot be changed")
+	// ErrUnknownPriv возвращается для неизвестного имени привилегии.
+	ErrUnknownPriv = errors.New("unknown privilege")
+	// ErrNoRoles возвращается при попытке оставить участника группы без ролей.
+	ErrNoRoles = errors.New("at least one role is required")
+)
+
+// Privileges – эффективные привилегии пользователя в канале: объединение флагов всех его статусов.
+type Privileges struct {
+	EditingPriv          bool `json:"editing_priv"`
+	DeletionPriv         bool `json:"deletion_priv"`
+	WritingPriv          bool `json:"writing_priv"`
+	AdminPriv            bool `json:"admin_priv"`
+	ManagingUsersPriv    bool `json:"managing_users_priv"`
+	PinningMessagesPriv  bool `json:"pinning_messages_priv"`
+	ReadingPriv          bool `json:"reading_priv"`
+	InvitingPriv         bool `json:"inviting_priv"`
+	ViewingAnalyticsPriv bool `json:"viewing_analytics_priv"`
+	ChannelEditPriv      bool `json:"channel_edit_priv"`
+}
+
+// Has сообщает, есть ли привилегия priv (имя колонки, например PrivWriting).
+func (p Privileges) Has(priv string) bool {
+	switch priv {
+	case PrivEditing:
+		return p.EditingPriv
+	case PrivDeletion:
+		return p.DeletionPriv
+	case PrivWriting:
+		return p.WritingPriv
+	case PrivAdmin:
+		return p.AdminPriv
+	case PrivManagingUsers:
+		return p.ManagingUsersPriv
+	case PrivPinningMessages:
+		return p.PinningMessagesPriv
+	case PrivReading:
+		return p.ReadingPriv
+	case PrivInviting:
+		return p.InvitingPriv
+	case PrivViewingAnalytics:
+		return p.ViewingAnalyticsPriv
+	case PrivChannelEdit:
+		return p.ChannelEditPriv
+	}
+	return false
+}
+
+// privColumns – все привилегии в порядке объявления в models.Status.
+var privColumns = []string{
+	PrivEditing, PrivDeletion, PrivWriting, PrivAdmin, PrivManagingUsers,
+	PrivPinningMessages, PrivReading, PrivInviting, PrivViewingAnalytics, PrivChannelEdit,
+}
+
+var (
+	// directPrivileges – привилегии участника личного чата (ролей в личных чатах нет).
+	directPrivileges = Privileges{WritingPriv: true, ReadingPriv: true, PinningMessagesPriv: true}
+	// publicPrivileges – привилегии участника публичного канала, которому не назначено ни одной роли.
+	// Участник закрытой группы без ролей не получает никаких привилегий.
+	publicPrivileges = Privileges{ReadingPriv: true}
+)
+
+// defaultRoles возвращает встроенные роли новой группы. В публичном канале роль member
+// («Подписчик») даёт только право чтения: писать могут владелец и администраторы.
+func defaultRoles(chatID uint, public bool) []models.Status {
+	member := models.Status{
+		Name: "Участник", Builtin: RoleMember, ChannelID: chatID,
+		WritingPriv: true, ReadingPriv: true, InvitingPriv: true,
+	}
+	if public {
+		member = models.Status{Name: "Подписчик", Builtin: RoleMember, ChannelID: chatID, ReadingPriv: true}
+	}
+	return []models.Status{
+		{
+			Name: "Владелец", Builtin: RoleOwner, ChannelID: chatID,
+			EditingPriv: true, DeletionPriv: true, WritingPriv: true, AdminPriv: true, ManagingUsersPriv: true,
+			PinningMessagesPriv: true, ReadingPriv: true, InvitingPriv: true, ViewingAnalyticsPriv: true, ChannelEditPriv: true,
+		},
+		{
+			Name: "Администратор", Builtin: RoleAdmin, ChannelID: chatID,
+			EditingPriv: true, DeletionPriv: true, WritingPriv: true, ManagingUsersPriv: true,
+			PinningMessagesPriv: true, ReadingPriv: true, InvitingPriv: true, ViewingAnalyticsPriv: true, ChannelEditPriv: true,
+		},
+		member,
+	}
+}
+
+// createDefaultRoles создаёт встроенные роли группы, назначает роль owner создателю,
+// а роль member – остальным участникам.
+func createDefaultRoles(tx *gorm.DB, chatID, ownerID uint, memberIDs []uint, public bool) error {
+	roles := defaultRoles(chatID, public)
+	if err := tx.Create(&roles).Error; err != nil {
+		return err
+	}
+	// При создании gorm заменяет false значениями по умолчанию (true) – записываем флаги явно.
+	for i := range roles {
+		if err := tx.Model(&roles[i]).Select("writing_priv", "reading_priv").Updates(&roles[i]).Error; err != nil {
+			return err
+		}
+	}
+	if err := assignRole(tx, ownerID, roles[0].ID); err != nil {
+		return err
+	}
+	for _, id := range memberIDs {
+		if id == ownerID {
+			continue
+		}
+		if err := assignRole(tx, id, roles[2].ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// assignRole назначает пользователю роль; повторное назначение не является ошибкой.
+func assignRole(tx *gorm.DB, userID, statusID uint) error {
+	return tx.Exec("INSERT INTO user_statuses (user_id, status_id) VALUES (?, ?) ON CONFLICT DO NOTHING",
+		userID, statusID).Error
+}
+
+// backfillDefaultRoles создаёт встроенные роли группам, созданным до появления ролей.
+func backfillDefaultRoles() error {
+	var groups []models.Channel
+	err := DB.Preload("Users").
+		Where("is_group = true AND NOT EXISTS (SELECT 1 FROM statuses WHERE statuses.channel_id = channels.id)").
+		Find(&groups).Error
+	if err != nil {
+		return err
+	}
+	for _, g := range groups {
+		ids := make([]uint, 0, len(g.Users))
+		for _, u := range g.Users {
+			ids = append(ids, u.ID)
+		}
+		if err := DB.Transaction(func(tx *gorm.DB) error {
+			return createDefaultRoles(tx, g.ID, g.CreatorID, ids, !g.IsPrivate)
+		}); err != nil {
+			return fmt.Errorf("group %d: %w", g.ID, err)
+		}
+	}
+	return nil
+}
+
+// GetChannelPrivileges возвращает эффективные привилегии всех участников канала.
+// В личных чатах у обоих участников одинаковые фиксированные привилегии; в группах
+// привилегии объединяются по всем назначенным ролям. Участник без ролей может только читать публичный канал,
+// а в закрытой группе не имеет привилегий.
+func GetChannelPrivileges(chatID uint) (map[uint]Privileges, error) {
+	return channelPrivileges(chatID, 0)
+}
+
+// GetPrivileges возвращает эффективные привилегии пользователя в канале.
+// Для пользователя, не состоящего в канале, все привилегии отсутствуют.
+func GetPrivileges(userID, chatID uint) Privileges {
+	privs, err := channelPrivileges(chatID, userID)
+	if err != nil {
+		return Privileges{}
+	}
+	return privs[userID]
+}
+
+// HasChannelPriv проверяет, есть ли у пользователя в канале привилегия priv (например, PrivEditing).
+func HasChannelPriv(userID, chatID uint, priv string) bool {
+	return GetPrivileges(userID, chatID).Has(priv)
+}
+
+// CanWrite проверяет, может ли пользователь отправлять сообщения в канал.
+func CanWrite(userID, chatID uint) bool {
+	return HasChannelPriv(userID, chatID, PrivWriting)
+}
+
+// ReadableChannelIDs возвращает каналы, в которых пользователь имеет ReadingPriv, одним запросом.
+// Правила совпадают с resolvePrivileges: личные чаты читаются всегда, в группах право даёт одна из ролей,
+// а участник публичного канала без ролей может читать его по умолчанию.
+func ReadableChannelIDs(userID uint) ([]uint, error) {
+	const roles = `SELECT 1 FROM user_statuses
+		JOIN statuses ON statuses.id = user_statuses.status_id AND statuses.deleted_at IS NULL
+		WHERE user_statuses.user_id = user_channels.user_id AND statuses.channel_id = channels.id`
+	var ids []uint
+	err := DB.Model(&models.Channel{}).
+		Joins("JOIN user_channels ON user_channels.channel_id = channels.id AND user_channels.user_id = ?", userID).
+		Where("channels.is_group = false OR EXISTS ("+roles+" AND statuses.reading_priv) OR "+
+			"(channels.is_private = false AND NOT EXISTS ("+roles+"))").
+		Pluck("channels.id", &ids).Error
+	return ids, err
+}
+
+// channelPrivileges вычисляет привилегии участников канала; если userID не равен нулю –
+// только для этого пользователя.
+func channelPrivileges(chatID, userID uint) (map[uint]Privileges, error) {
+	members := DB.Table("user_channels").Select("user_id").Where("channel_id = ?", chatID)
+	if userID != 0 {
+		members = members.Where("user_id = ?", userID)
+	}
+	var memberIDs []uint
+	if err := members.Pluck("user_id", &memberIDs).Error; err != nil {
+		return nil, err
+	}
+	if len(memberIDs) == 0 {
+		return map[uint]Privileges{}, nil
+	}
+
+	chat := GetChatByID(chatID)
+	if !chat.IsGroup {
+		return resolvePrivileges(chat, memberIDs, nil), nil
+	}
+
+	selects := "user_statuses.user_id"
+	for _, col := range privColumns {
+		selects += fmt.Sprintf(", BOOL_OR(statuses.%s) AS %s", col, col)
+	}
+	var rows []struct {
+		UserID uint
+		Privileges
+	}
+	err := DB.Table("statuses").
+		Select(selects).
+		Joins("JOIN user_statuses ON user_statuses.status_id = statuses.id").
+		Where("statuses.channel_id = ? AND statuses.deleted_at IS NULL AND user_statuses.user_id IN ?", chatID, memberIDs).
+		Group("user_statuses.user_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	granted := make(map[uint]Privileges, len(rows))
+	for _, row := range rows {
+		granted[row.UserID] = row.Privileges
+	}
+	return resolvePrivileges(chat, memberIDs, granted), nil
+}
+
+// resolvePrivileges вычисляет привилегии участников memberIDs канала chat по объединённым
+// привилегиям их ролей granted (ключ – ID пользователя).
+func resolvePrivileges(chat models.Channel, memberIDs []uint, granted map[uint]Privileges) map[uint]Privileges {
+	result := make(map[uint]Privileges, len(memberIDs))
+	for _, id := range memberIDs {
+		switch privs, ok := granted[id]; {
+		case !chat.IsGroup:
+			result[id] = directPrivileges
+		case ok:
+			result[id] = privs
+		case !chat.IsPrivate:
+			result[id] = publicPrivileges
+		default:
+			result[id] = Privileges{}
+		}
+	}
+	return result
+}
+
+// GetRoles возвращает роли канала вместе с назначенными пользователями.
+func GetRoles(chatID uint) ([]models.Status, map[uint][]uint, error) {
+	var roles []models.Status
+	if err := DB.Where("channel_id = ?", chatID).Order("id").Find(&roles).Error; err != nil {
+		return nil, nil, err
+	}
+	var rows []struct {
+		StatusID uint
+		UserID   uint
+	}
+	err := DB.Table("user_statuses").
+		Select("user_statuses.status_id, user_statuses.user_id").
+		Joins("JOIN statuses ON statuses.id = user_statuses.status_id").
+		Joins("JOIN user_channels ON user_channels.channel_id = statuses.channel_id AND user_channels.user_id = user_statuses.user_id").
+		Where("statuses.channel_id = ? AND statuses.deleted_at IS NULL", chatID).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, nil, err
+	}
+	assigned := make(map[uint][]uint)
+	for _, row := range rows {
+		assigned[row.StatusID] = append(assigned[row.StatusID], row.UserID)
+	}
+	return roles, assigned, nil
+}
+
+// GetRole возвращает роль канала по ID.
+func GetRole(chatID, roleID uint) (models.Status, error) {
+	var role models.Status
+	err := DB.Where("channel_id = ?", chatID).First(&role, roleID).Error
+	return role, err
+}
+
+// GetRoleByBuiltin возвращает встроенную роль канала (RoleOwner, RoleAdmin или RoleMember).
+func GetRoleByBuiltin(chatID uint, builtin string) (models.Status, error) {
+	var role models.Status
+	err := DB.Where("channel_id = ? AND builtin = ?", chatID, builtin).First(&role).Error
+	return role, err
+}
+
+// privUpdates проверяет имена привилегий и формирует набор колонок для обновления.
+func privUpdates(privs map[string]bool) (map[string]interface{}, error) {
+	updates := make(map[string]interface{}, len(privs))
+	for name, value := range privs {
+		if !isPrivColumn(name) {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownPriv, name)
+		}
+		updates[name] = value
+	}
+	return updates, nil
+}
+
+// isPrivColumn сообщает, является ли name именем колонки привилегии.
+func isPrivColumn(name string) bool {
+	for _, col := range privColumns {
+		if col == name {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateRole создаёт пользовательскую роль в канале.
+//
+// Параметры:
+//   - chatID: идентификатор канала.
+//   - name: название роли.
+//   - privs: привилегии роли по именам колонок; неуказанные привилегии принимают значения по умолчанию.
+func CreateRole(chatID uint, name string, privs map[string]bool) (models.Status, error) {
+	updates, err := privUpdates(privs)
+	if err != nil {
+		return models.Status{}, err
+	}
+	role := models.Status{Name: name, ChannelID: chatID}
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&role).Error; err != nil {
+			return err
+		}
+		// Значения false нужно записать отдельно: при создании gorm заменяет их значениями по умолчанию.
+		if len(updates) == 0 {
+			return nil
+		}
+		return tx.Model(&role).Updates(updates).Error
+	})
+	if err != nil {
+		return models.Status{}, err
+	}
+	return GetRole(chatID, role.ID)
+}
+
+// UpdateRole изменяет название и привилегии роли. Роль owner изменить нельзя.
+func UpdateRole(chatID, roleID uint, name string, privs map[string]bool) (models.Status, error) {
+	role, err := GetRole(chatID, roleID)
+	if err != nil {
+		return models.Status{}, err
+	}
+	if role.Builtin == RoleOwner {
+		return models.Status{}, ErrBuiltinRole
+	}
+	updates, err := privUpdates(privs)
+	if err != nil {
+		return models.Status{}, err
+	}
+	if name != "" {
+		updates["name"] = name
+	}
+	if len(updates) > 0 {
+		if err := DB.Model(&role).Updates(updates).Error; err != nil {
+			return models.Status{}, err
+		}
+	}
+	return GetRole(chatID, roleID)
+}
+
+// DeleteRole удаляет пользовательскую роль и снимает её со всех пользователей.
+// Участники, у которых не осталось других ролей, получают встроенную роль member.
+func DeleteRole(chatID, roleID uint) error {
+	role, err := GetRole(chatID, roleID)
+	if err != nil {
+		return err
+	}
+	if role.Builtin != "" {
+		return ErrBuiltinRole
+	}
+	member, err := GetRoleByBuiltin(chatID, RoleMember)
+	if err != nil {
+		return err
+	}
+	return DB.Transaction(func(tx *gorm.DB) error {
+		var holders []uint
+		if err := tx.Table("user_statuses").Where("status_id = ?", role.ID).Pluck("user_id", &holders).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM user_statuses WHERE status_id = ?", role.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&role).Error; err != nil {
+			return err
+		}
+		for _, userID := range holders {
+			err := tx.Exec(`INSERT INTO user_statuses (user_id, status_id)
+				SELECT ?, ? WHERE NOT EXISTS (
+					SELECT 1 FROM user_statuses JOIN statuses ON statuses.id = user_statuses.status_id
+					WHERE user_statuses.user_id = ? AND statuses.channel_id = ? AND statuses.deleted_at IS NULL)`,
+				userID, member.ID, userID, chatID).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// SetUserRoles заменяет роли пользователя в канале на roleIDs.
+// Роль owner нельзя назначить или снять: у владельца она сохраняется всегда.
+// Пустой список ролей отклоняется с ErrNoRoles: участник без ролей потерял бы доступ к группе.
+func SetUserRoles(chatID, userID uint, roleIDs []uint) error {
+	if len(roleIDs) == 0 {
+		return ErrNoRoles
+	}
+	var roles []models.Status
+	if err := DB.Where("channel_id = ? AND id IN ?", chatID, uniqueIDs(roleIDs)).Find(&roles).Error; err != nil {
+		return err
+	}
+	if len(roles) != len(uniqueIDs(roleIDs)) {
+		return gorm.ErrRecordNotFound
+	}
+	for _, role := range roles {
+		if role.Builtin == RoleOwner {
+			return ErrBuiltinRole
+		}
+	}
+
+	return DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec(`DELETE FROM user_statuses WHERE user_id = ? AND status_id IN
+			(SELECT id FROM statuses WHERE channel_id = ? AND builtin <> ?)`, userID, chatID, RoleOwner).Error
+		if err != nil {
+			return err
+		}
+		for _, role := range roles {
+			if err := assignRole(tx, userID, role.ID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// IsChannelOwner проверяет, назначена ли пользователю роль owner в канале.
+func IsChannelOwner(userID, chatID uint) bool {
+	var count int64
+	DB.Table("user_statuses").
+		Joins("JOIN statuses ON statuses.id = user_statuses.status_id").
+		Where("user_statuses.user_id = ? AND statuses.channel_id = ? AND statuses.builtin = ? AND statuses.deleted_at IS NULL",
+			userID, chatID, RoleOwner).
+		Count(&count)
+	return count > 0
+}
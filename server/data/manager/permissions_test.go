@@ -0,0 +1,246 @@
+//go:build integration
+
+// Тесты пакета manager работают с настоящей базой PostgreSQL: при инициализации пакет подключается
+// к DatabaseUrl, а env требует переменные окружения сервиса. Запуск в окружении docker-compose:
+//
+//	go test -tags integration ./server/...
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"orion/server/data/models"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	Migrate()
+	os.Exit(m.Run())
+}
+
+// newTestUser создаёт пользователя с уникальными именем и почтой.
+func newTestUser(t *testing.T) uint {
+	t.Helper()
+	name := fmt.Sprintf("test_%s_%d", t.Name(), time.Now().UnixNano())
+	user := models.User{Mail: name + "@example.com", UserName: name, Password: "x", LastOnline: time.Now()}
+	if err := CreateUser(&user); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	return user.ID
+}
+
+// newTestGroup создаёт группу владельца owner с участниками members.
+func newTestGroup(t *testing.T, owner uint, members []uint, public bool) uint {
+	t.Helper()
+	chat, err := CreateGroup(owner, "test", "", members, public)
+	if err != nil {
+		t.Fatalf("create group: %v", err)
+	}
+	return chat.ID
+}
+
+func TestPrivilegesHas(t *testing.T) {
+	all := Privileges{
+		EditingPriv: true, DeletionPriv: true, WritingPriv: true, AdminPriv: true, ManagingUsersPriv: true,
+		PinningMessagesPriv: true, ReadingPriv: true, InvitingPriv: true, ViewingAnalyticsPriv: true, ChannelEditPriv: true,
+	}
+	for _, priv := range privColumns {
+		if !all.Has(priv) {
+			t.Errorf("Has(%q) = false for all privileges", priv)
+		}
+		if (Privileges{}).Has(priv) {
+			t.Errorf("Has(%q) = true for no privileges", priv)
+		}
+	}
+	if !(Privileges{ReadingPriv: true}).Has(PrivReading) || (Privileges{ReadingPriv: true}).Has(PrivWriting) {
+		t.Error("Has must check only the requested privilege")
+	}
+	if all.Has("unknown_priv") {
+		t.Error("Has must be false for unknown privilege")
+	}
+}
+
+func TestResolvePrivileges(t *testing.T) {
+	roleless := uint(1)
+	withRole := uint(2)
+	granted := map[uint]Privileges{withRole: {ReadingPriv: true, PinningMessagesPriv: true}}
+
+	tests := []struct {
+		name string
+		chat models.Channel
+		want map[uint]Privileges
+	}{
+		{
+			name: "direct chat ignores roles",
+			chat: models.Channel{IsGroup: false, IsPrivate: true},
+			want: map[uint]Privileges{roleless: directPrivileges, withRole: directPrivileges},
+		},
+		{
+			name: "private group member without roles has no privileges",
+			chat: models.Channel{IsGroup: true, IsPrivate: true},
+			want: map[uint]Privileges{roleless: {}, withRole: granted[withRole]},
+		},
+		{
+			name: "public channel member without roles can only read",
+			chat: models.Channel{IsGroup: true, IsPrivate: false},
+			want: map[uint]Privileges{roleless: {ReadingPriv: true}, withRole: granted[withRole]},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := resolvePrivileges(tt.chat, []uint{roleless, withRole}, granted)
+			for id, want := range tt.want {
+				if got[id] != want {
+					t.Errorf("user %d: got %+v, want %+v", id, got[id], want)
+				}
+			}
+			if len(got) != len(tt.want) {
+				t.Errorf("got %d entries, want %d", len(got), len(tt.want))
+			}
+		})
+	}
+}
+
+func TestBuiltinRolePrivileges(t *testing.T) {
+	owner, member := newTestUser(t), newTestUser(t)
+	outsider := newTestUser(t)
+
+	tests := []struct {
+		name   string
+		public bool
+		user   uint
+		priv   string
+		want   bool
+	}{
+		{"owner can edit channel", false, owner, PrivChannelEdit, true},
+		{"group member can write", false, member, PrivWriting, true},
+		{"group member cannot manage users", false, member, PrivManagingUsers, false},
+		{"subscriber can read", true, member, PrivReading, true},
+		{"subscriber cannot write", true, member, PrivWriting, false},
+		{"outsider cannot read", false, outsider, PrivReading, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chatID := newTestGroup(t, owner, []uint{member}, tt.public)
+			if got := HasChannelPriv(tt.user, chatID, tt.priv); got != tt.want {
+				t.Errorf("HasChannelPriv(%s) = %v, want %v", tt.priv, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetUserRolesRejectsEmpty(t *testing.T) {
+	owner, member := newTestUser(t), newTestUser(t)
+	chatID := newTestGroup(t, owner, []uint{member}, true)
+
+	if err := SetUserRoles(chatID, member, nil); !errors.Is(err, ErrNoRoles) {
+		t.Fatalf("SetUserRoles(nil) = %v, want ErrNoRoles", err)
+	}
+	if CanWrite(member, chatID) {
+		t.Error("subscriber gained write access")
+	}
+	if !HasChannelPriv(member, chatID, PrivReading) {
+		t.Error("subscriber lost read access")
+	}
+}
+
+func TestSetUserRolesProtectsOwner(t *testing.T) {
+	owner, member := newTestUser(t), newTestUser(t)
+	chatID := newTestGroup(t, owner, []uint{member}, false)
+	ownerRole, err := GetRoleByBuiltin(chatID, RoleOwner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	memberRole, err := GetRoleByBuiltin(chatID, RoleMember)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := SetUserRoles(chatID, member, []uint{ownerRole.ID}); !errors.Is(err, ErrBuiltinRole) {
+		t.Errorf("assigning owner role: got %v, want ErrBuiltinRole", err)
+	}
+	if IsChannelOwner(member, chatID) {
+		t.Error("member became owner")
+	}
+
+	if err := SetUserRoles(chatID, owner, []uint{memberRole.ID}); err != nil {
+		t.Fatalf("SetUserRoles(owner): %v", err)
+	}
+	if !IsChannelOwner(owner, chatID) {
+		t.Error("owner lost the owner role")
+	}
+	if _, err := UpdateRole(chatID, ownerRole.ID, "", map[string]bool{PrivAdmin: false}); !errors.Is(err, ErrBuiltinRole) {
+		t.Errorf("updating owner role: got %v, want ErrBuiltinRole", err)
+	}
+	if err := DeleteRole(chatID, memberRole.ID); !errors.Is(err, ErrBuiltinRole) {
+		t.Errorf("deleting member role: got %v, want ErrBuiltinRole", err)
+	}
+}
+
+func TestDeleteRoleKeepsMembersRestricted(t *testing.T) {
+	owner, member := newTestUser(t), newTestUser(t)
+	chatID := newTestGroup(t, owner, []uint{member}, true)
+
+	readOnly, err := CreateRole(chatID, "read-only", map[string]bool{PrivReading: true, PrivWriting: false})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := SetUserRoles(chatID, member, []uint{readOnly.ID}); err != nil {
+		t.Fatal(err)
+	}
+	if err := DeleteRole(chatID, readOnly.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if CanWrite(member, chatID) {
+		t.Error("member gained write access after the role was deleted")
+	}
+	if !HasChannelPriv(member, chatID, PrivReading) {
+		t.Error("member lost read access after the role was deleted")
+	}
+	_, holders, err := GetRoles(chatID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	memberRole, err := GetRoleByBuiltin(chatID, RoleMember)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, id := range holders[memberRole.ID] {
+		found = found || id == member
+	}
+	if !found {
+		t.Error("member was not reassigned the builtin member role")
+	}
+}
+
+func TestReadableChannelIDsMatchesPrivileges(t *testing.T) {
+	owner, member := newTestUser(t), newTestUser(t)
+	private := newTestGroup(t, owner, []uint{member}, false)
+	public := newTestGroup(t, owner, []uint{member}, true)
+
+	noRead, err := CreateRole(private, "no-read", map[string]bool{PrivReading: false, PrivWriting: false})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := SetUserRoles(private, member, []uint{noRead.ID}); err != nil {
+		t.Fatal(err)
+	}
+
+	ids, err := ReadableChannelIDs(member)
+	if err != nil {
+		t.Fatal(err)
+	}
+	readable := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		readable[id] = true
+	}
+	for _, chatID := range []uint{private, public} {
+		if want := HasChannelPriv(member, chatID, PrivReading); readable[chatID] != want {
+			t.Errorf("chat %d: ReadableChannelIDs = %v, HasChannelPriv = %v", chatID, readable[chatID], want)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package manager
+
+import (
+	"orion/server/data/models"
+
+	"gorm.io/gorm/clause"
+)
+
+// PinMessage закрепляет сообщение в канале. Повторное закрепление не является ошибкой.
+//
+// Возвращаемые значения:
+//   - bool: true, если сообщение было закреплено этим вызовом.
+//   - error: ошибка записи в базу данных.
+func PinMessage(chatID, messageID, userID uint) (bool, error) {
+	pin := models.PinnedMessage{ChannelID: chatID, MessageID: messageID, PinnedBy: userID}
+	res := DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&pin)
+	return res.RowsAffected > 0, res.Error
+}
+
+// UnpinMessage открепляет сообщение в канале.
+//
+// Возвращаемые значения:
+//   - bool: true, если сообщение было откреплено этим вызовом.
+//   - error: ошибка записи в базу данных.
+func UnpinMessage(chatID, messageID uint) (bool, error) {
+	res := DB.Where("channel_id = ? AND message_id = ?", chatID, messageID).
+		Delete(&models.PinnedMessage{})
+	return res.RowsAffected > 0, res.Error
+}
+
+// GetPinnedMessages возвращает закреплённые сообщения канала, начиная с последнего закреплённого.
+// Сообщения, удалённые для всех, не возвращаются.
+func GetPinnedMessages(chatID uint) ([]models.Message, error) {
+	var msgs []models.Message
+	err := DB.Model(&models.Message{}).
+		Select("messages.*").
+		Joins("JOIN pinned_messages ON pinned_messages.message_id = messages.id").
+		Where("pinned_messages.channel_id = ?", chatID).
+		Order("pinned_messages.created_at DESC").
+		Find(&msgs).Error
+	return msgs, err
+}
+
+// GetPinnedIDs возвращает ID закреплённых сообщений канала, начиная с последнего закреплённого.
+func GetPinnedIDs(chatID uint) ([]uint, error) {
+	ids := []uint{}
+	err := DB.Model(&models.PinnedMessage{}).
+		Where("channel_id = ?", chatID).
+		Order("created_at DESC").
+		Pluck("message_id", &ids).Error
+	return ids, err
+}
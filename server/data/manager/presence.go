@@ -0,0 +1,82 @@
+package manager
+
+import (
+	"orion/server/data/models"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+// TouchPresence создаёт или обновляет запись о присутствии пользователя на реплике.
+//
+// Параметры:
+//   - userID: идентификатор пользователя.
+//   - nodeID: идентификатор реплики.
+//   - sessions: количество сессий пользователя на реплике.
+func TouchPresence(userID uint, nodeID string, sessions int) error {
+	presence := models.Presence{UserID: userID, NodeID: nodeID, Sessions: sessions, UpdatedAt: time.Now()}
+	return DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "node_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"sessions", "updated_at"}),
+	}).Create(&presence).Error
+}
+
+// RemovePresence удаляет запись о присутствии пользователя на реплике.
+func RemovePresence(userID uint, nodeID string) error {
+	return DB.Where("user_id = ? AND node_id = ?", userID, nodeID).Delete(&models.Presence{}).Error
+}
+
+// CleanupPresence удаляет записи, не обновлявшиеся дольше ttl (например, оставшиеся после падения реплики).
+func CleanupPresence(ttl time.Duration) error {
+	return DB.Where("updated_at < ?", time.Now().Add(-ttl)).Delete(&models.Presence{}).Error
+}
+
+// OnlineUserIDs возвращает тех пользователей из userIDs, у которых есть актуальная запись
+// о присутствии на любой реплике, кроме exceptNode.
+//
+// Параметры:
+//   - userIDs: проверяемые пользователи.
+//   - exceptNode: реплика, присутствие на которой не учитывается (обычно текущая – она знает его точно).
+//   - ttl: срок, в течение которого запись считается актуальной.
+func OnlineUserIDs(userIDs []uint, exceptNode string, ttl time.Duration) ([]uint, error) {
+	if len(userIDs) == 0 {
+		return nil, nil
+	}
+	var ids []uint
+	err := DB.Model(&models.Presence{}).
+		Distinct("user_id").
+		Where("user_id IN ? AND node_id != ? AND updated_at >= ?", userIDs, exceptNode, time.Now().Add(-ttl)).
+		Pluck("user_id", &ids).Error
+	return ids, err
+}
+
+// GetContactIDs возвращает пользователей, состоящих хотя бы в одном общем личном чате или закрытой
+// группе с userID, исключая самого пользователя и тех, с кем у него есть блокировка в любую сторону.
+// Общие публичные каналы контактом не считаются: подписчики канала не видят присутствие друг друга.
+func GetContactIDs(userID uint) ([]uint, error) {
+	var ids []uint
+	err := DB.Table("user_channels AS other").
+		Distinct("other.user_id").
+		Joins("JOIN user_channels AS mine ON mine.channel_id = other.channel_id AND mine.user_id = ?", userID).
+		Joins("JOIN channels ON channels.id = other.channel_id").
+		Where("other.user_id != ?", userID).
+		Where("NOT (channels.is_group AND NOT channels.is_private)").
+		Where(`NOT EXISTS (SELECT 1 FROM user_blocks
+			WHERE (blocker_id = ? AND blocked_id = other.user_id) OR (blocker_id = other.user_id AND blocked_id = ?))`,
+			userID, userID).
+		Pluck("other.user_id", &ids).Error
+	return ids, err
+}
+
+// GetLastOnline возвращает время последней активности пользователей.
+func GetLastOnline(userIDs []uint) (map[uint]time.Time, error) {
+	var users []models.User
+	if err := DB.Select("id", "last_online").Where("id IN ?", userIDs).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	result := make(map[uint]time.Time, len(users))
+	for _, u := range users {
+		result[u.ID] = u.LastOnline
+	}
+	return result, nil
+}
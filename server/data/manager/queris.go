@@ -2,6 +2,7 @@ package manager
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -9,8 +10,8 @@ import (
 	"orion/server/data/models"
 	"orion/server/services/env"
 	"os"
-	"sort"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -106,16 +107,46 @@ func GetChatIDForUsers(user1ID, user2ID uint) int {
 	return channelIDs[0]
 }
 
-// ReadMessages помечает сообщения в указанном чате как прочитанные для заданного пользователя.
+// MarkMessagesRead сдвигает курсор прочтения пользователя в указанном чате.
 //
-// Функция обновляет все сообщения в канале с идентификатором chatid,
-// где пользователь не является отправителем и флаг readed равен false.
+// Курсор только увеличивается: если upToID не больше текущего курсора, ничего не меняется.
+// Если upToID равен нулю или превышает ID последнего сообщения чата, курсор ставится на последнее сообщение.
 //
 // Параметры:
-//   - chatid: идентификатор чата (канала).
-//   - userid: идентификатор пользователя, для которого устанавливается флаг прочтения.
-func ReadMessages(chatid float64, userid string) {
-	DB.Model(&models.Message{}).Where("user_id != ? and channel_id = ? and readed = false", userid, chatid).Update("readed", true)
+//   - chatID: идентификатор чата (канала).
+//   - userID: идентификатор прочитавшего пользователя.
+//   - upToID: ID последнего прочитанного сообщения (0 – все сообщения).
+//
+// Возвращаемые значения:
+//   - uint: новый ID последнего прочитанного сообщения (0, если курсор не сдвинулся).
+//   - error: ошибка обновления.
+func MarkMessagesRead(chatID, userID, upToID uint) (uint, error) {
+	var lastID uint
+	if err := DB.Model(&models.Message{}).
+		Select("COALESCE(MAX(id), 0)").
+		Where("channel_id = ?", chatID).
+		Scan(&lastID).Error; err != nil {
+		return 0, err
+	}
+	if upToID == 0 || upToID > lastID {
+		upToID = lastID
+	}
+	if upToID == 0 || upToID <= GetReadCursor(userID, chatID) {
+		return 0, nil
+	}
+	if err := advanceReadCursor(DB, userID, chatID, upToID); err != nil {
+		return 0, err
+	}
+	return upToID, nil
+}
+
+// IsChatMember проверяет, состоит ли пользователь в чате.
+func IsChatMember(chatID, userID uint) bool {
+	var count int64
+	DB.Table("user_channels").
+		Where("channel_id = ? AND user_id = ?", chatID, userID).
+		Count(&count)
+	return count > 0
 }
 
 // GetChatByID возвращает информацию о чате (канале) по его ID.
@@ -148,43 +179,87 @@ func GetChannels(userid uint) ([]models.Channel, error) {
 	return channels, nil
 }
 
-// GetChanMassages возвращает список сообщений, принадлежащих каналу, отсортированных по времени отправки (возрастание).
+// GetChanMassages возвращает страницу сообщений канала, упорядоченных по возрастанию ID.
+// Сообщения, удалённые для всех или скрытые пользователем userID, не возвращаются.
+//
+// Если задан after, возвращаются первые limit сообщений с ID больше after; иначе – последние limit
+// сообщений с ID меньше before (before = 0 – самые новые сообщения).
 //
 // Параметры:
 //   - chanid: уникальный идентификатор канала.
+//   - userID: идентификатор пользователя, запрашивающего историю.
+//   - before, after: курсоры – ID сообщений, относительно которых выбирается страница.
+//   - limit: максимальное количество сообщений на странице.
 //
 // Возвращаемые значения:
 //   - []Message: слайс сообщений канала.
+//   - bool: true, если в направлении выборки есть ещё сообщения.
 //   - error: ошибка, если произошла неудача при получении данных.
-func GetChanMassages(chanid uint) ([]models.Message, error) {
+func GetChanMassages(chanid uint, userID uint, before, after uint, limit int) ([]models.Message, bool, error) {
+	query := DB.Where("channel_id = ?", chanid).
+		Where(notHiddenFor, userID).
+		Limit(limit + 1)
+	if after > 0 {
+		query = query.Where("id > ?", after).Order("id")
+	} else {
+		if before > 0 {
+			query = query.Where("id < ?", before)
+		}
+		query = query.Order("id DESC")
+	}
+
 	var message []models.Message
-	err := DB.Model(&models.Channel{ID: chanid}).Association("Messages").Find(&message)
-	if err != nil {
+	if err := query.Find(&message).Error; err != nil {
 		log.Print("GetChanMassages" + err.Error())
 		log.Println(chanid)
-		return nil, err
+		return nil, false, err
 	}
-	sort.Slice(message, func(i, j int) bool {
-		return message[i].Timestamp.Before(message[j].Timestamp)
-	})
-	return message, nil
-
+	hasMore := len(message) > limit
+	if hasMore {
+		message = message[:limit]
+	}
+	if after == 0 {
+		for i, j := 0, len(message)-1; i < j; i, j = i+1, j-1 {
+			message[i], message[j] = message[j], message[i]
+		}
+	}
+	return message, hasMore, nil
 }
 
+// notHiddenFor – условие, исключающее сообщения, скрытые пользователем («удалить у меня»).
+const notHiddenFor = "NOT EXISTS (SELECT 1 FROM hidden_messages WHERE hidden_messages.message_id = messages.id AND hidden_messages.user_id = ?)"
+
 // AddMessage добавляет новое сообщение в указанный чат.
 //
+// Сообщению присваивается следующий порядковый номер канала (Seq). Если передан clientMsgID
+// и у отправителя уже есть сообщение с таким идентификатором, новое сообщение не создаётся,
+// а возвращается сохранённое ранее (даже если его уже удалили для всех) – это делает повторную
+// отправку идемпотентной. Если идентификатор уже использован в другом чате, возвращается
+// ErrClientMsgIDConflict.
+//
 // Параметры:
 //   - froid: идентификатор пользователя (отправителя сообщения).
 //   - chaid: идентификатор чата (канала), куда отправляется сообщение.
 //   - message: текст сообщения.
+//   - clientMsgID: идентификатор, сгенерированный клиентом (может быть пустым).
+//   - replyToID: ID сообщения, на которое дан ответ (0 – сообщение не является ответом).
 //
-// В случае ошибки при сохранении сообщения в базу данных, ошибка логируется.
-func AddMessage(froid uint, chaid uint, message string) error {
+// Возвращаемые значения:
+//   - Message: сохранённое сообщение.
+//   - bool: true, если сообщение было создано ранее и вызов является повтором.
+//   - error: ErrClientMsgIDConflict, ошибка, если пользователи заблокированы или сообщение не удалось сохранить.
+func AddMessage(froid uint, chaid uint, message string, clientMsgID string, replyToID uint) (models.Message, bool, error) {
+	if clientMsgID != "" {
+		if existing, ok, err := findClientMessage(froid, chaid, clientMsgID); ok || err != nil {
+			return existing, ok, err
+		}
+	}
+
 	// Проверяем, является ли чат личным
 	var chat models.Channel
 	DB.Preload("Users").First(&chat, chaid)
 
-	if chat.IsPrivate && len(chat.Users) == 2 {
+	if !chat.IsGroup && len(chat.Users) == 2 {
 		var otherUserID uint
 		for _, u := range chat.Users {
 			if u.ID != froid {
@@ -194,7 +269,7 @@ func AddMessage(froid uint, chaid uint, message string) error {
 		}
 
 		if IsBlocked(froid, otherUserID) {
-			return fmt.Errorf("user is blocked")
+			return models.Message{}, false, fmt.Errorf("user is blocked")
 		}
 	}
 
@@ -204,7 +279,62 @@ func AddMessage(froid uint, chaid uint, message string) error {
 		Content:   message,
 		Timestamp: time.Now(),
 	}
-	return DB.Create(&mess).Error
+	if clientMsgID != "" {
+		mess.ClientMsgID = &clientMsgID
+	}
+	if replyToID != 0 {
+		mess.ReplyToID = &replyToID
+	}
+
+	if err := insertMessage(&mess); err != nil {
+		// Параллельный повтор с тем же clientMsgID мог успеть сохранить сообщение первым.
+		if clientMsgID != "" {
+			if existing, ok, findErr := findClientMessage(froid, chaid, clientMsgID); ok || findErr != nil {
+				return existing, ok, findErr
+			}
+		}
+		return models.Message{}, false, err
+	}
+	return mess, false, nil
+}
+
+// insertMessage сохраняет сообщение, присваивая ему следующий порядковый номер канала.
+func insertMessage(mess *models.Message) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		return insertMessageTx(tx, mess)
+	})
+}
+
+// insertMessageTx сохраняет сообщение в транзакции tx.
+func insertMessageTx(tx *gorm.DB, mess *models.Message) error {
+	// Увеличение last_seq блокирует строку канала до конца транзакции, поэтому номера не повторяются.
+	if err := tx.Raw("UPDATE channels SET last_seq = last_seq + 1 WHERE id = ? RETURNING last_seq", mess.ChannelID).
+		Scan(&mess.Seq).Error; err != nil {
+		return err
+	}
+	if err := tx.Create(mess).Error; err != nil {
+		return err
+	}
+	// Своё сообщение отправитель уже прочитал
+	return advanceReadCursor(tx, mess.UserID, mess.ChannelID, mess.ID)
+}
+
+// ErrClientMsgIDConflict возвращается, если отправитель повторно использовал clientMsgID в другом чате.
+var ErrClientMsgIDConflict = errors.New("clientMsgId is already used in another chat")
+
+// findClientMessage ищет сообщение отправителя по клиентскому идентификатору, включая удалённые для всех:
+// уникальный индекс по (user_id, client_msg_id) распространяется и на них.
+// Найденное сообщение из другого чата – не повтор, а конфликт (ErrClientMsgIDConflict).
+func findClientMessage(userID, chatID uint, clientMsgID string) (models.Message, bool, error) {
+	var msg models.Message
+	err := DB.Unscoped().Where("user_id = ? AND client_msg_id = ?", userID, clientMsgID).Limit(1).Find(&msg).Error
+	if err != nil || msg.ID == 0 {
+		return models.Message{}, false, err
+	}
+	if msg.ChannelID != chatID {
+		return models.Message{}, false, ErrClientMsgIDConflict
+	}
+	return msg, true, nil
 }
 
 // AddHexPhoto обновляет фотографию профиля пользователя.
@@ -216,7 +346,7 @@ func AddMessage(froid uint, chaid uint, message string) error {
 //
 // При возникновении ошибки обновления записи, ошибка логируется.
 func AddHexPhoto(userid uint, hex string) {
-	err := DB.Model(&models.User{}).Where("id = ?", userid).Update("profile_picture", hex)
+	err := DB.Model(&models.User{}).Where("id = ?", userid).Update("profile_picture", hex).Error
 	if err != nil {
 		log.Printf("Some error occured. Err: %s", err)
 	}
@@ -267,21 +397,6 @@ func CreateChat(userID1, userID2 uint, channelName string) (*models.Channel, err
 	return &chat, nil
 }
 
-// SearchByUsername выполняет поиск пользователей по началу их имени пользователя.
-// Используется оператор LIKE с подстановкой, чтобы найти всех пользователей, имя которых начинается с заданной строки.
-//
-// Параметры:
-//   - username: начальная часть имени пользователя для поиска.
-//
-// Возвращаемое значение:
-//   - []User: слайс найденных пользователей.
-func SearchByUsername(username string) []models.User {
-	var user []models.User
-	query := username + "%"
-	DB.Where("user_name LIKE ?", query).Find(&user)
-	return user
-}
-
 // GetUsersInChat возвращает список пользователей, участвующих в указанном чате.
 //
 // Параметры:
@@ -299,11 +414,7 @@ func GetUsersInChat(chatid uint) ([]models.User, error) {
 	return users, nil
 }
 
-// IfReadedChat проверяет, есть ли в указанном чате непрочитанные сообщения для пользователя.
-//
-// Функция подсчитывает количество сообщений в канале с chatid,
-// которые были отправлены другими пользователями, не прочитаны (readed = false)
-// и не удалены (deleted_at IS NULL).
+// IfReadedChat проверяет, прочитал ли пользователь все сообщения чата.
 //
 // Параметры:
 //   - chatid: уникальный идентификатор чата.
@@ -312,18 +423,7 @@ func GetUsersInChat(chatid uint) ([]models.User, error) {
 // Возвращаемое значение:
 //   - bool: возвращает true, если все сообщения прочитаны (или их нет), иначе false.
 func IfReadedChat(chatid uint, userID uint) bool {
-	var count int64
-	err := DB.Model(&models.Message{}).
-		Where("\"messages\".\"channel_id\" = ?", chatid).
-		Where("\"messages\".\"user_id\" != ?", userID).
-		Where("\"messages\".\"readed\" = false").
-		Where("\"messages\".\"deleted_at\" IS NULL").
-		Count(&count).Error
-
-	if err != nil || count > 0 {
-		return false // есть непрочитанные сообщения
-	}
-	return true // нет непрочитанных сообщений
+	return GetUnreadCount(chatid, userID) == 0
 }
 
 // CreateUser сохраняет нового пользователя в базе данных.
@@ -340,12 +440,21 @@ func CreateUser(user *models.User) error {
 // UpdateUser обновляет информацию о существующем пользователе.
 // Перед вызовом функции предполагается, что user содержит уже существующий ID.
 // Метод Save обновляет все поля записи.
-func UpdateUser(userID uint, Mail, UserName, Bio string) error {
+// DisplayName со значением nil оставляет отображаемое имя без изменений.
+func UpdateUser(userID uint, Mail, UserName, Bio string, DisplayName *string) error {
 	// Если требуется обновлять не все поля, можно использовать метод DB.Model().Updates(...)
-	err := DB.Model(&models.User{}).Where("id = ?", userID).Update("bio", Bio).Update("mail", Mail).Update("user_name", UserName)
+	updates := map[string]interface{}{
+		"bio":       Bio,
+		"mail":      Mail,
+		"user_name": UserName,
+	}
+	if DisplayName != nil {
+		updates["display_name"] = *DisplayName
+	}
+	err := DB.Model(&models.User{}).Where("id = ?", userID).Updates(updates).Error
 	if err != nil {
 		log.Printf("Error updating user: %v", err)
-		return err.Error
+		return err
 	}
 	return nil
 }
@@ -434,8 +543,34 @@ func IsBlocked(user1ID, user2ID uint) bool {
 	return count > 0
 }
 
-// CheckIfBlocked проверяет взаимную блокировку в чате
+// GetBlockedIDs возвращает пользователей, с которыми у userID есть блокировка в любую сторону.
+func GetBlockedIDs(userID uint) map[uint]bool {
+	var rows []struct {
+		BlockerID uint
+		BlockedID uint
+	}
+	DB.Table("user_blocks").
+		Select("blocker_id, blocked_id").
+		Where("blocker_id = ? OR blocked_id = ?", userID, userID).
+		Scan(&rows)
+
+	blocked := make(map[uint]bool, len(rows))
+	for _, row := range rows {
+		if row.BlockerID == userID {
+			blocked[row.BlockedID] = true
+		} else {
+			blocked[row.BlockerID] = true
+		}
+	}
+	return blocked
+}
+
+// CheckIfBlocked проверяет взаимную блокировку в личном чате.
+// В групповых чатах блокировка не запрещает писать, поэтому для них всегда возвращается false.
 func CheckIfBlocked(chatID uint, userID uint) (bool, error) {
+	if GetChatByID(chatID).IsGroup {
+		return false, nil
+	}
 	users, err := GetUsersInChat(chatID)
 	if err != nil {
 		return false, err
@@ -459,10 +594,86 @@ func UpdateLastOnline(userID uint, lastOnline time.Time) error {
 		Update("last_online", lastOnline).Error
 }
 
+// GetUnreadCount возвращает количество сообщений других пользователей после курсора прочтения userID.
 func GetUnreadCount(chatID, userID uint) int {
 	var count int64
 	DB.Model(&models.Message{}).
-		Where("channel_id = ? AND user_id != ? AND readed = false", chatID, userID).
+		Where("channel_id = ? AND user_id != ? AND id > ?", chatID, userID, GetReadCursor(userID, chatID)).
+		Where(notHiddenFor, userID).
 		Count(&count)
 	return int(count)
 }
+
+// GetMessagesSince возвращает сообщения из чатов пользователя, отправленные после указанных курсоров.
+// Используется для досылки сообщений, пропущенных клиентом за время отключения.
+//
+// Параметры:
+//   - userID: идентификатор пользователя; учитываются только чаты, в которых он состоит.
+//   - lastID: глобальный курсор – ID последнего увиденного сообщения (0 – не задан).
+//   - chatCursors: курсоры по отдельным чатам; имеют приоритет над глобальным курсором.
+//   - limit: максимальное количество сообщений.
+//
+// Возвращаемые значения:
+//   - []Message: сообщения, упорядоченные по возрастанию ID.
+//   - error: ошибка запроса к базе данных.
+func GetMessagesSince(userID, lastID uint, chatCursors map[uint]uint, limit int) ([]models.Message, error) {
+	var conds []string
+	var args []interface{}
+	chatIDs := make([]uint, 0, len(chatCursors))
+	for chatID, cursor := range chatCursors {
+		conds = append(conds, "(messages.channel_id = ? AND messages.id > ?)")
+		args = append(args, chatID, cursor)
+		chatIDs = append(chatIDs, chatID)
+	}
+	if lastID > 0 {
+		if len(chatIDs) > 0 {
+			conds = append(conds, "(messages.channel_id NOT IN ? AND messages.id > ?)")
+			args = append(args, chatIDs, lastID)
+		} else {
+			conds = append(conds, "messages.id > ?")
+			args = append(args, lastID)
+		}
+	}
+	if len(conds) == 0 {
+		return nil, nil
+	}
+
+	var messages []models.Message
+	err := DB.Model(&models.Message{}).
+		Select("messages.*").
+		Joins("JOIN user_channels ON user_channels.channel_id = messages.channel_id AND user_channels.user_id = ?", userID).
+		Where(strings.Join(conds, " OR "), args...).
+		Where(notHiddenFor, userID).
+		Order("messages.id").
+		Limit(limit).
+		Find(&messages).Error
+	if err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// GetReadState возвращает для каждого чата пользователя наибольший курсор прочтения среди
+// остальных участников: сообщения с ID не больше него прочитаны хотя бы одним собеседником.
+// Чаты, где другие участники ещё ничего не прочитали, в результат не попадают.
+func GetReadState(userID uint) (map[uint]uint, error) {
+	var rows []struct {
+		ChannelID  uint
+		LastReadID uint
+	}
+	err := DB.Table("read_cursors").
+		Select("read_cursors.channel_id, MAX(read_cursors.last_read_id) AS last_read_id").
+		Joins("JOIN user_channels ON user_channels.channel_id = read_cursors.channel_id AND user_channels.user_id = ?", userID).
+		Where("read_cursors.user_id != ?", userID).
+		Group("read_cursors.channel_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	state := make(map[uint]uint, len(rows))
+	for _, row := range rows {
+		state[row.ChannelID] = row.LastReadID
+	}
+	return state, nil
+}
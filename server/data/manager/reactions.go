@@ -0,0 +1,91 @@
+package manager
+
+import (
+	"orion/server/data/models"
+
+	"gorm.io/gorm/clause"
+)
+
+// ReactionCount – агрегированное число реакций одним эмодзи на сообщение.
+//
+// Поля структуры:
+//   - Emoji: эмодзи реакции.
+//   - Count: количество пользователей, поставивших реакцию.
+//   - Mine: поставил ли реакцию пользователь, для которого выполнялся запрос.
+type ReactionCount struct {
+	Emoji string `json:"emoji"`
+	Count int    `json:"count"`
+	Mine  bool   `json:"mine"`
+}
+
+// AddReaction добавляет реакцию пользователя на сообщение.
+//
+// Возвращаемые значения:
+//   - bool: true, если реакция была добавлена (false – такая реакция уже стояла).
+//   - error: ошибка записи в базу данных.
+func AddReaction(messageID, userID uint, emoji string) (bool, error) {
+	reaction := models.Reaction{MessageID: messageID, UserID: userID, Emoji: emoji}
+	res := DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&reaction)
+	return res.RowsAffected > 0, res.Error
+}
+
+// RemoveReaction удаляет реакцию пользователя на сообщение.
+//
+// Возвращаемые значения:
+//   - bool: true, если реакция была удалена (false – такой реакции не было).
+//   - error: ошибка записи в базу данных.
+func RemoveReaction(messageID, userID uint, emoji string) (bool, error) {
+	res := DB.Where("message_id = ? AND user_id = ? AND emoji = ?", messageID, userID, emoji).
+		Delete(&models.Reaction{})
+	return res.RowsAffected > 0, res.Error
+}
+
+// CountReactions возвращает количество реакций эмодзи emoji на сообщение.
+func CountReactions(messageID uint, emoji string) int {
+	var count int64
+	DB.Model(&models.Reaction{}).
+		Where("message_id = ? AND emoji = ?", messageID, emoji).
+		Count(&count)
+	return int(count)
+}
+
+// GetReactionCounts возвращает агрегированные реакции для списка сообщений.
+//
+// Параметры:
+//   - messageIDs: идентификаторы сообщений.
+//   - userID: пользователь, для которого заполняется признак Mine.
+//
+// Возвращаемые значения:
+//   - map[uint][]ReactionCount: реакции по ID сообщения, упорядоченные по времени первой реакции.
+//   - error: ошибка запроса к базе данных.
+func GetReactionCounts(messageIDs []uint, userID uint) (map[uint][]ReactionCount, error) {
+	result := make(map[uint][]ReactionCount)
+	if len(messageIDs) == 0 {
+		return result, nil
+	}
+
+	var rows []struct {
+		MessageID uint
+		Emoji     string
+		Count     int
+		Mine      bool
+	}
+	err := DB.Model(&models.Reaction{}).
+		Select("message_id, emoji, COUNT(*) AS count, BOOL_OR(user_id = ?) AS mine", userID).
+		Where("message_id IN ?", messageIDs).
+		Group("message_id, emoji").
+		Order("MIN(created_at)").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		result[row.MessageID] = append(result[row.MessageID], ReactionCount{
+			Emoji: row.Emoji,
+			Count: row.Count,
+			Mine:  row.Mine,
+		})
+	}
+	return result, nil
+}
@@ -0,0 +1,75 @@
+package manager
+
+import (
+	"log"
+	"orion/server/data/models"
+
+	"gorm.io/gorm"
+)
+
+// GetReadCursor возвращает ID последнего прочитанного пользователем сообщения в канале (0 – ничего не прочитано).
+func GetReadCursor(userID, chatID uint) uint {
+	var cursor models.ReadCursor
+	DB.Where("user_id = ? AND channel_id = ?", userID, chatID).Limit(1).Find(&cursor)
+	return cursor.LastReadID
+}
+
+// GetChannelCursors возвращает курсоры прочтения всех участников канала.
+func GetChannelCursors(chatID uint) (map[uint]uint, error) {
+	var cursors []models.ReadCursor
+	err := DB.Joins("JOIN user_channels ON user_channels.channel_id = read_cursors.channel_id AND user_channels.user_id = read_cursors.user_id").
+		Where("read_cursors.channel_id = ?", chatID).
+		Find(&cursors).Error
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[uint]uint, len(cursors))
+	for _, c := range cursors {
+		result[c.UserID] = c.LastReadID
+	}
+	return result, nil
+}
+
+// GetMessageReaders возвращает участников канала (кроме автора), прочитавших сообщение.
+func GetMessageReaders(msg models.Message) ([]models.User, error) {
+	var users []models.User
+	err := DB.Joins("JOIN read_cursors ON read_cursors.user_id = users.id").
+		Joins("JOIN user_channels ON user_channels.channel_id = read_cursors.channel_id AND user_channels.user_id = users.id").
+		Where("read_cursors.channel_id = ? AND read_cursors.last_read_id >= ? AND users.id != ?", msg.ChannelID, msg.ID, msg.UserID).
+		Order("users.id").
+		Find(&users).Error
+	return users, err
+}
+
+// advanceReadCursor сдвигает курсор пользователя вперёд до upToID; курсор никогда не уменьшается.
+func advanceReadCursor(tx *gorm.DB, userID, chatID, upToID uint) error {
+	return tx.Exec(`INSERT INTO read_cursors (user_id, channel_id, last_read_id, updated_at) VALUES (?, ?, ?, now())
+		ON CONFLICT (user_id, channel_id) DO UPDATE
+		SET last_read_id = GREATEST(read_cursors.last_read_id, EXCLUDED.last_read_id), updated_at = now()`,
+		userID, chatID, upToID).Error
+}
+
+// backfillReadCursors переносит состояние прочтения из устаревшего флага messages.readed в курсоры.
+// Выполняется один раз, пока таблица read_cursors пуста: курсор участника ставится на последнее
+// сообщение, которое он отправил или которое было отмечено прочитанным из сообщений других участников.
+func backfillReadCursors() {
+	var count int64
+	if err := DB.Model(&models.ReadCursor{}).Count(&count).Error; err != nil || count > 0 {
+		return
+	}
+	if !DB.Migrator().HasColumn(&models.Message{}, "readed") {
+		return
+	}
+	err := DB.Exec(`
+		INSERT INTO read_cursors (user_id, channel_id, last_read_id, updated_at)
+		SELECT user_channels.user_id, user_channels.channel_id, MAX(messages.id), now()
+		FROM user_channels
+		JOIN messages ON messages.channel_id = user_channels.channel_id
+		WHERE messages.user_id = user_channels.user_id
+		   OR (messages.user_id != user_channels.user_id AND messages.readed = true)
+		GROUP BY user_channels.user_id, user_channels.channel_id
+		ON CONFLICT DO NOTHING`).Error
+	if err != nil {
+		log.Printf("Migrate: backfill read cursors: %v", err)
+	}
+}
@@ -0,0 +1,130 @@
+package manager
+
+import (
+	"html"
+	"orion/server/data/models"
+	"strings"
+	"time"
+)
+
+// messageSearchVector – выражение полнотекстового индекса по тексту сообщений. Пользователи пишут
+// по-русски и по-английски, поэтому текст разбирается обеими конфигурациями. Выражение должно
+// совпадать с индексом idx_messages_content_search, иначе индекс не будет использован.
+const messageSearchVector = "(to_tsvector('russian', messages.content) || to_tsvector('english', messages.content))"
+
+// Границы совпадений во фрагментах ts_headline. Это управляющие символы: они удаляются из текста
+// перед построением фрагмента, поэтому не могут встретиться в самом сообщении и после
+// HTML-экранирования заменяются на теги <mark>.
+const (
+	headlineStartSel = "\x01"
+	headlineStopSel  = "\x02"
+)
+
+// messageHeadlineOptions – параметры ts_headline для фрагментов с подсветкой совпадений.
+const messageHeadlineOptions = "StartSel=" + headlineStartSel + ", StopSel=" + headlineStopSel +
+	", MaxWords=30, MinWords=10, MaxFragments=2, FragmentDelimiter=\" … \""
+
+// headlineContent – текст сообщения без символов-границ для ts_headline.
+const headlineContent = "translate(messages.content, chr(1) || chr(2), '')"
+
+// snippetMarkup заменяет границы совпадений тегами подсветки.
+var snippetMarkup = strings.NewReplacer(headlineStartSel, "<mark>", headlineStopSel, "</mark>")
+
+// MessageSearchFilter – условия поиска сообщений.
+//
+// Поля структуры:
+//   - Query: поисковый запрос в синтаксисе websearch_to_tsquery (слова, "фразы", -исключения, or).
+//   - ChatID: искать только в этом чате (0 – во всех доступных).
+//   - AuthorID: искать только сообщения этого автора (0 – любого).
+//   - From, To: границы времени отправки [From, To); нулевое значение – без ограничения.
+//   - Before: курсор – искать сообщения с ID меньше него (0 – самые новые).
+//   - Limit: максимальное количество результатов.
+type MessageSearchFilter struct {
+	Query    string
+	ChatID   uint
+	AuthorID uint
+	From     time.Time
+	To       time.Time
+	Before   uint
+	Limit    int
+}
+
+// MessageHit – найденное сообщение.
+//
+// Поля структуры:
+//   - ID, ChatID, UserID, Timestamp: идентификатор сообщения, чат, автор и время отправки.
+//   - Snippet: HTML-фрагмент текста: текст сообщения экранирован, совпадения обрамлены тегами <mark>…</mark>.
+type MessageHit struct {
+	ID        uint      `json:"id"`
+	ChatID    uint      `json:"chatId"`
+	UserID    uint      `json:"userId"`
+	Timestamp time.Time `json:"timestamp"`
+	Snippet   string    `json:"snippet"`
+}
+
+// SearchMessages ищет сообщения по тексту среди чатов chatIDs.
+//
+// Учитываются только обычные сообщения: удалённые для всех, скрытые пользователем userID
+// и служебные сообщения в выдачу не попадают. Результаты упорядочены от новых к старым.
+//
+// Параметры:
+//   - userID: пользователь, выполняющий поиск.
+//   - chatIDs: чаты, в которых пользователю разрешено искать.
+//   - f: условия поиска.
+//
+// Возвращаемые значения:
+//   - []MessageHit: найденные сообщения.
+//   - bool: true, если есть ещё более старые результаты.
+//   - error: ошибка запроса к базе данных.
+func SearchMessages(userID uint, chatIDs []uint, f MessageSearchFilter) ([]MessageHit, bool, error) {
+	hits := []MessageHit{}
+	if len(chatIDs) == 0 || strings.TrimSpace(f.Query) == "" {
+		return hits, false, nil
+	}
+
+	q := DB.Model(&models.Message{}).
+		Select(`messages.id, messages.channel_id AS chat_id, messages.user_id, messages.timestamp,
+			CASE WHEN to_tsvector('russian', messages.content) @@ websearch_to_tsquery('russian', ?)
+				THEN ts_headline('russian', `+headlineContent+`, websearch_to_tsquery('russian', ?), ?)
+				ELSE ts_headline('english', `+headlineContent+`, websearch_to_tsquery('english', ?), ?)
+			END AS snippet`, f.Query, f.Query, messageHeadlineOptions, f.Query, messageHeadlineOptions).
+		Where(messageSearchVector+" @@ (websearch_to_tsquery('russian', ?) || websearch_to_tsquery('english', ?))", f.Query, f.Query).
+		Where("messages.channel_id IN ?", chatIDs).
+		Where("messages.system = false").
+		Where(notHiddenFor, userID)
+	if f.ChatID != 0 {
+		q = q.Where("messages.channel_id = ?", f.ChatID)
+	}
+	if f.AuthorID != 0 {
+		q = q.Where("messages.user_id = ?", f.AuthorID)
+	}
+	if !f.From.IsZero() {
+		q = q.Where("messages.timestamp >= ?", f.From)
+	}
+	if !f.To.IsZero() {
+		q = q.Where("messages.timestamp < ?", f.To)
+	}
+	if f.Before != 0 {
+		q = q.Where("messages.id < ?", f.Before)
+	}
+
+	err := q.Order("messages.id DESC").
+		Limit(f.Limit + 1).
+		Scan(&hits).Error
+	if err != nil {
+		return nil, false, err
+	}
+	hasMore := len(hits) > f.Limit
+	if hasMore {
+		hits = hits[:f.Limit]
+	}
+	for i := range hits {
+		hits[i].Snippet = highlightSnippet(hits[i].Snippet)
+	}
+	return hits, hasMore, nil
+}
+
+// highlightSnippet экранирует фрагмент ts_headline для вставки в HTML и заменяет границы совпадений тегами <mark>.
+func highlightSnippet(headline string) string {
+	return snippetMarkup.Replace(html.EscapeString(headline))
+}
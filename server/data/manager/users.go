@@ -0,0 +1,94 @@
+package manager
+
+import (
+	"orion/server/data/models"
+	"strings"
+)
+
+// UserHit – пользователь, найденный поиском по справочнику.
+//
+// Поля структуры:
+//   - ID, UserName, DisplayName: идентификатор, имя пользователя и отображаемое имя.
+//   - ProfilePicture: хэш фото профиля в MinIO.
+//   - ChatID: ID личного чата с пользователем, выполнявшим поиск (-1, если чата нет).
+//   - IsContact: есть ли у пользователей личный чат.
+//   - Score: релевантность; результаты упорядочены по её убыванию.
+type UserHit struct {
+	ID             uint
+	UserName       string
+	DisplayName    string
+	ProfilePicture string
+	ChatID         int
+	IsContact      bool
+	Score          float64
+}
+
+// UserSearchCursor – позиция, с которой продолжается выдача: Score и ID последнего результата страницы.
+type UserSearchCursor struct {
+	Score float64
+	ID    uint
+}
+
+// SearchUsers выполняет нечёткий поиск пользователей по имени пользователя и отображаемому имени.
+//
+// Совпадение ищется без учёта регистра по подстроке и по сходству триграмм (pg_trgm), поэтому
+// находятся и имена с опечатками. Выше всего ранжируются собеседники, с которыми есть личный чат,
+// затем совпадения по началу имени, по подстроке и по сходству. Пользователи, заблокировавшие
+// userID, и сам userID в выдачу не попадают.
+//
+// Параметры:
+//   - userID: пользователь, выполняющий поиск.
+//   - query: строка поиска.
+//   - after: курсор предыдущей страницы (nil – первая страница).
+//   - limit: максимальное количество результатов.
+//
+// Возвращаемые значения:
+//   - []UserHit: найденные пользователи.
+//   - bool: true, если есть следующая страница.
+//   - error: ошибка запроса к базе данных.
+func SearchUsers(userID uint, query string, after *UserSearchCursor, limit int) ([]UserHit, bool, error) {
+	hits := []UserHit{}
+	if query = strings.TrimSpace(query); query == "" {
+		return hits, false, nil
+	}
+	prefix := escapeLike(query) + "%"
+	substring := "%" + prefix
+
+	ranked := DB.Model(&models.User{}).
+		Select(`users.id, users.user_name, users.display_name, users.profile_picture,
+			COALESCE(dm.channel_id, -1) AS chat_id,
+			dm.channel_id IS NOT NULL AS is_contact,
+			(CASE WHEN dm.channel_id IS NOT NULL THEN 2 ELSE 0 END
+				+ CASE WHEN users.user_name ILIKE ? OR users.display_name ILIKE ? THEN 0.5 ELSE 0 END
+				+ CASE WHEN users.user_name ILIKE ? OR users.display_name ILIKE ? THEN 0.5 ELSE 0 END
+				+ GREATEST(similarity(users.user_name, ?), similarity(users.display_name, ?)))::float8 AS score`,
+			prefix, prefix, substring, substring, query, query).
+		Joins(`LEFT JOIN LATERAL (
+			SELECT own.channel_id FROM user_channels own
+			JOIN user_channels other ON other.channel_id = own.channel_id
+			JOIN channels ON channels.id = own.channel_id
+			WHERE own.user_id = ? AND other.user_id = users.id AND channels.is_group = false
+			LIMIT 1) AS dm ON true`, userID).
+		Where("users.user_name ILIKE ? OR users.display_name ILIKE ? OR users.user_name % ? OR users.display_name % ?",
+			substring, substring, query, query).
+		Where("users.id != ?", userID).
+		// В user_blocks колонки «перевёрнуты» (см. User.BlockedUsers): blocked_id – кто заблокировал, blocker_id – кого.
+		// Скрываются пользователи, заблокировавшие userID.
+		Where("NOT EXISTS (SELECT 1 FROM user_blocks WHERE user_blocks.blocked_id = users.id AND user_blocks.blocker_id = ?)", userID)
+
+	q := DB.Table("(?) AS ranked", ranked)
+	if after != nil {
+		q = q.Where("ranked.score < ? OR (ranked.score = ? AND ranked.id > ?)", after.Score, after.Score, after.ID)
+	}
+	err := q.Order("ranked.score DESC, ranked.id").
+		Limit(limit + 1).
+		Scan(&hits).Error
+	if err != nil {
+		return nil, false, err
+	}
+	hasMore := len(hits) > limit
+	if hasMore {
+		hits = hits[:limit]
+	}
+	return hits, hasMore, nil
+}
@@ -0,0 +1,30 @@
+//go:build integration
+
+package manager
+
+import "testing"
+
+func TestSearchUsersHidesBlockers(t *testing.T) {
+	caller, blocker, blocked := newTestUser(t), newTestUser(t), newTestUser(t)
+	if err := BlockUser(blocker, caller); err != nil {
+		t.Fatal(err)
+	}
+	if err := BlockUser(caller, blocked); err != nil {
+		t.Fatal(err)
+	}
+
+	hits, _, err := SearchUsers(caller, "test_"+t.Name(), nil, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := make(map[uint]bool, len(hits))
+	for _, hit := range hits {
+		found[hit.ID] = true
+	}
+	if found[blocker] {
+		t.Error("user who blocked the caller is returned")
+	}
+	if !found[blocked] {
+		t.Error("user blocked by the caller is hidden")
+	}
+}
@@ -16,6 +16,7 @@ import (
 //   - LastOnline: Время последней активности пользователя (обязательное поле).
 //   - ProfilePicture: Ссылка или код изображения профиля (текстовое поле, по умолчанию пустое).
 //   - Bio: Биография пользователя, ограниченная 255 символами (по умолчанию пустая).
+//   - DisplayName: Отображаемое имя пользователя, не обязано быть уникальным (по умолчанию пустое).
 //
 // Связи:
 //   - Channels: Множество каналов, в которых состоит пользователь (многие ко многим через таблицу user_channels).
@@ -32,6 +33,8 @@ type User struct {
 	Bio            string    `gorm:"type:varchar(255);default:''"` // Био пользователя
 	BlockingUpTo   time.Time `gorm:"not null;default:CURRENT_TIMESTAMP"`
 
+	DisplayName string `gorm:"type:varchar(64);not null;default:''"` // Отображаемое имя пользователя
+
 	Channels     []Channel `gorm:"many2many:user_channels;"` // Множество каналов, в которых состоит пользователь
 	Statuses     []Status  `gorm:"many2many:user_statuses;"` // Множество статусов пользователя в каналах
 	BlockedUsers []User    `gorm:"many2many:user_blocks;joinForeignKey:BlockedID;joinReferences:BlockerID"`
@@ -44,7 +47,11 @@ type User struct {
 //   - Name: Уникальное имя канала (обязательное поле).
 //   - Description: Описание канала (текстовое поле, по умолчанию пустое).
 //   - IsPrivate: Флаг приватности канала (по умолчанию false).
+//   - IsGroup: Флаг группового чата; личные чаты двух пользователей имеют IsGroup = false.
+//   - Title: Отображаемое название группы (Name остаётся уникальным техническим именем).
+//   - Avatar: Хэш изображения аватара группы или канала в MinIO (по умолчанию пустой).
 //   - CreatorID: Идентификатор пользователя, создавшего канал (обязательное поле).
+//   - LastSeq: Порядковый номер последнего сообщения канала (используется для нумерации сообщений).
 //
 // Связи:
 //   - Creator: Пользователь, создавший канал (отношение «один к одному», внешний ключ – CreatorID).
@@ -57,7 +64,11 @@ type Channel struct {
 	Name        string    `gorm:"unique;not null"`             // Уникальное имя канала
 	Description string    `gorm:"type:text;default:''"`        // Описание канала
 	IsPrivate   bool      `gorm:"default:false"`               // Приватность канала
+	IsGroup     bool      `gorm:"default:false"`               // Групповой чат (false – личный чат двух пользователей)
+	Title       string    `gorm:"size:128;default:''"`         // Отображаемое название группы
+	Avatar      string    `gorm:"type:text;default:''"`        // Хэш аватара канала в MinIO
 	CreatorID   uint      `gorm:"not null"`                    // ID создателя канала
+	LastSeq     uint64    `gorm:"not null;default:0"`          // Номер последнего сообщения канала
 	Creator     User      `gorm:"foreignKey:CreatorID"`        // Связь с создателем канала
 	Users       []User    `gorm:"many2many:user_channels;"`    // Пользователи, участвующие в канале
 	Messages    []Message `gorm:"constraint:OnDelete:CASCADE"` // Сообщения канала
@@ -83,6 +94,9 @@ type Channel struct {
 //   - ViewingAnalyticsPriv: Право просматривать аналитику (по умолчанию false).
 //   - ChannelEditPriv: Право редактировать канал (по умолчанию false).
 //
+// Встроенные роли (Builtin = owner, admin, member) создаются вместе с группой и не могут быть удалены;
+// роль owner также нельзя изменить или назначить.
+//
 // Связь:
 //   - Channel: Канал, к которому принадлежит статус.
 type Status struct {
@@ -103,6 +117,8 @@ type Status struct {
 	InvitingPriv         bool `gorm:"default:false"` // Право приглашать пользователей
 	ViewingAnalyticsPriv bool `gorm:"default:false"` // Право просмотра аналитики
 	ChannelEditPriv      bool `gorm:"default:false"` // Право редактирования канала
+
+	Builtin string `gorm:"size:16;default:''"` // Встроенная роль: owner, admin, member или "" для пользовательской
 }
 
 // Message представляет сообщение, отправленное в канале.
@@ -114,7 +130,12 @@ type Status struct {
 //   - Content: Текстовое содержимое сообщения (обязательное поле).
 //   - Timestamp: Время отправки сообщения (обязательное поле).
 //   - Edited: Флаг, указывающий, было ли сообщение изменено (по умолчанию false).
-//   - Readed: Флаг, указывающий, прочитано ли сообщение (по умолчанию false).
+//   - Seq: Порядковый номер сообщения в канале, монотонно возрастает.
+//   - ClientMsgID: Идентификатор, сгенерированный клиентом для повторной отправки без дублей
+//     (уникален в пределах отправителя, может отсутствовать).
+//   - ReplyToID: Идентификатор родительского сообщения того же канала, если сообщение является ответом.
+//   - ForwardedFromUserID, ForwardedFromChatID: Автор и канал оригинала, если сообщение переслано.
+//   - System: Флаг служебного сообщения; UserID у него – пользователь, вызвавший событие.
 //
 // Связи:
 //   - Channel: Канал, к которому принадлежит сообщение (внешний ключ – ChannelID).
@@ -129,5 +150,119 @@ type Message struct {
 	Content   string    `gorm:"type:text;not null"`       // Содержимое сообщения
 	Timestamp time.Time `gorm:"not null"`                 // Время отправки сообщения
 	Edited    bool      `gorm:"default:false"`            // Было ли сообщение изменено
-	Readed    bool      `gorm:"default:false"`            // Было ли сообщение прочитано
+
+	Seq         uint64  `gorm:"not null;default:0"` // Номер сообщения в канале
+	ClientMsgID *string `gorm:"type:varchar(64)"`   // Клиентский ID сообщения (уникален вместе с UserID)
+	ReplyToID   *uint   `gorm:"index"`              // ID сообщения, на которое дан ответ
+
+	ForwardedFromUserID *uint // ID автора пересланного сообщения
+	ForwardedFromChatID *uint // ID канала, из которого переслано сообщение
+
+	System bool `gorm:"default:false"` // Служебное сообщение (например, об изменении настроек канала)
+}
+
+// Presence представляет присутствие пользователя на одной из реплик сервера.
+// Запись существует, пока у пользователя есть WebSocket-сессии на реплике NodeID,
+// и периодически обновляется; записи с устаревшим UpdatedAt (например, после падения реплики) не учитываются.
+//
+// Поля структуры:
+//   - UserID: Идентификатор пользователя.
+//   - NodeID: Идентификатор реплики сервера.
+//   - Sessions: Количество сессий пользователя на реплике.
+//   - UpdatedAt: Время последнего подтверждения присутствия.
+type Presence struct {
+	UserID    uint      `gorm:"primaryKey;autoIncrement:false"` // ID пользователя
+	NodeID    string    `gorm:"primaryKey;type:varchar(128)"`   // ID реплики
+	Sessions  int       `gorm:"not null"`                       // Количество сессий на реплике
+	UpdatedAt time.Time `gorm:"not null;index"`                 // Время последнего подтверждения
+}
+
+// MessageEdit хранит предыдущую версию отредактированного сообщения.
+//
+// Поля структуры:
+//   - ID: Уникальный идентификатор записи истории.
+//   - MessageID: Идентификатор отредактированного сообщения.
+//   - EditorID: Идентификатор пользователя, выполнившего редактирование.
+//   - PreviousContent: Текст сообщения до редактирования.
+//   - EditedAt: Время редактирования.
+type MessageEdit struct {
+	ID              uint      `gorm:"primaryKey;autoIncrement"` // Уникальный ID записи
+	MessageID       uint      `gorm:"not null;index"`           // ID сообщения
+	Message         Message   `gorm:"foreignKey:MessageID"`     // Связь с сообщением
+	EditorID        uint      `gorm:"not null"`                 // ID редактора
+	PreviousContent string    `gorm:"type:text;not null"`       // Текст до редактирования
+	EditedAt        time.Time `gorm:"not null"`                 // Время редактирования
+}
+
+// HiddenMessage отмечает сообщение, удалённое пользователем только у себя («удалить у меня»).
+// Сообщения, удалённые для всех, помечаются мягким удалением (DeletedAt) самого сообщения.
+//
+// Поля структуры:
+//   - UserID: Идентификатор пользователя, скрывшего сообщение.
+//   - MessageID: Идентификатор скрытого сообщения.
+//   - CreatedAt: Время удаления.
+type HiddenMessage struct {
+	UserID    uint      `gorm:"primaryKey;autoIncrement:false"` // ID пользователя
+	MessageID uint      `gorm:"primaryKey;autoIncrement:false"` // ID сообщения
+	CreatedAt time.Time // Время удаления
+}
+
+// Reaction представляет реакцию (эмодзи) пользователя на сообщение.
+// Пользователь может поставить на одно сообщение несколько разных эмодзи, но каждое – один раз.
+//
+// Поля структуры:
+//   - MessageID: Идентификатор сообщения.
+//   - UserID: Идентификатор пользователя, поставившего реакцию.
+//   - Emoji: Эмодзи реакции.
+//   - CreatedAt: Время добавления реакции.
+type Reaction struct {
+	MessageID uint      `gorm:"primaryKey;autoIncrement:false"` // ID сообщения
+	UserID    uint      `gorm:"primaryKey;autoIncrement:false"` // ID пользователя
+	Emoji     string    `gorm:"primaryKey;type:varchar(32)"`    // Эмодзи реакции
+	CreatedAt time.Time // Время добавления
+}
+
+// PinnedMessage представляет сообщение, закреплённое в канале.
+//
+// Поля структуры:
+//   - ChannelID: Идентификатор канала.
+//   - MessageID: Идентификатор закреплённого сообщения.
+//   - PinnedBy: Идентификатор пользователя, закрепившего сообщение.
+//   - CreatedAt: Время закрепления.
+type PinnedMessage struct {
+	ChannelID uint      `gorm:"primaryKey;autoIncrement:false"` // ID канала
+	MessageID uint      `gorm:"primaryKey;autoIncrement:false"` // ID сообщения
+	PinnedBy  uint      `gorm:"not null"`                       // ID закрепившего пользователя
+	CreatedAt time.Time // Время закрепления
+}
+
+// ReadCursor хранит, до какого сообщения пользователь прочитал канал.
+// Сообщение считается прочитанным пользователем, если его ID не больше LastReadID.
+//
+// Поля структуры:
+//   - UserID: Идентификатор пользователя.
+//   - ChannelID: Идентификатор канала.
+//   - LastReadID: ID последнего прочитанного сообщения.
+//   - UpdatedAt: Время последнего сдвига курсора.
+type ReadCursor struct {
+	UserID     uint      `gorm:"primaryKey;autoIncrement:false"` // ID пользователя
+	ChannelID  uint      `gorm:"primaryKey;autoIncrement:false"` // ID канала
+	LastReadID uint      `gorm:"not null;default:0"`             // ID последнего прочитанного сообщения
+	UpdatedAt  time.Time // Время последнего изменения
+}
+
+// MemberEvent – вступление пользователя в группу или канал либо выход из него.
+// Журнал нужен аналитике роста участников: записи в user_channels удаляются при выходе.
+//
+// Поля структуры:
+//   - ChannelID: Идентификатор канала.
+//   - UserID: Идентификатор пользователя.
+//   - Joined: true – вступление, false – выход или исключение.
+//   - CreatedAt: Время события.
+type MemberEvent struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement"`                            // Уникальный ID события
+	ChannelID uint      `gorm:"not null;index:idx_member_events_channel,priority:1"` // ID канала
+	UserID    uint      `gorm:"not null"`                                            // ID пользователя
+	Joined    bool      `gorm:"not null"`                                            // Вступление (true) или выход (false)
+	CreatedAt time.Time `gorm:"index:idx_member_events_channel,priority:2"`          // Время события
 }
@@ -0,0 +1,78 @@
+package chat
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"net/http"
+	"orion/server/data/manager"
+	"orion/server/services/jwt"
+	"strconv"
+	"time"
+)
+
+const (
+	// defaultAnalyticsDays – период аналитики по умолчанию.
+	defaultAnalyticsDays = 30
+	// maxAnalyticsDays – максимальная длина периода аналитики.
+	maxAnalyticsDays = 366
+)
+
+// GetAnalyticsHandler возвращает аналитику канала. Доступно участникам с ViewingAnalyticsPriv.
+//
+// Параметры запроса:
+//   - from: начало периода, дата в формате YYYY-MM-DD (UTC) включительно;
+//   - to: конец периода, дата в формате YYYY-MM-DD (UTC) включительно.
+//
+// По умолчанию возвращаются последние 30 дней, период не может превышать 366 дней.
+func GetAnalyticsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := jwt.ExtractJWT(w, r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	chatID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "invalid chat id", http.StatusBadRequest)
+		return
+	}
+	if !manager.IsChatMember(uint(chatID), userID) {
+		http.Error(w, "chat not found", http.StatusNotFound)
+		return
+	}
+	if !manager.HasChannelPriv(userID, uint(chatID), manager.PrivViewingAnalytics) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	to := today.AddDate(0, 0, 1)
+	from := to.AddDate(0, 0, -defaultAnalyticsDays)
+	if v := r.URL.Query().Get("to"); v != "" {
+		day, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			http.Error(w, "invalid to", http.StatusBadRequest)
+			return
+		}
+		to = day.AddDate(0, 0, 1)
+		from = to.AddDate(0, 0, -defaultAnalyticsDays)
+	}
+	if v := r.URL.Query().Get("from"); v != "" {
+		day, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			http.Error(w, "invalid from", http.StatusBadRequest)
+			return
+		}
+		from = day
+	}
+	if !from.Before(to) || to.Sub(from) > maxAnalyticsDays*24*time.Hour {
+		http.Error(w, "invalid period", http.StatusBadRequest)
+		return
+	}
+
+	report, err := manager.GetChannelAnalytics(uint(chatID), from, to)
+	if err != nil {
+		http.Error(w, "cannot compute analytics", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,156 @@
+package chat
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"net/http"
+	"orion/server/data/manager"
+	"orion/server/services/jwt"
+	"orion/server/services/ws"
+	"strconv"
+)
+
+const (
+	// defaultDirectoryLimit – размер страницы каталога каналов по умолчанию.
+	defaultDirectoryLimit = 20
+	// maxDirectoryLimit – максимальный размер страницы каталога каналов.
+	maxDirectoryLimit = 100
+	// previewMessages – количество последних сообщений в предпросмотре канала.
+	previewMessages = 50
+)
+
+// CreateChannelHandler создаёт публичный канал.
+// Тело запроса: {"title": "Новости", "description": "..."}.
+func CreateChannelHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := jwt.ExtractJWT(w, r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	type body struct {
+		Title       string `json:"title"`
+		Description string `json:"description"`
+	}
+	var b body
+	if err := json.NewDecoder(r.Body).Decode(&b); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+
+	chat, err := ws.WSmanager.CreateGroup(userID, b.Title, b.Description, nil, true)
+	if err != nil {
+		writeGroupError(w, err, "cannot create channel")
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":          chat.ID,
+		"title":       chat.Title,
+		"description": chat.Description,
+		"is_group":    chat.IsGroup,
+		"is_private":  chat.IsPrivate,
+		"subscribers": 1,
+	})
+}
+
+// ListChannelsHandler возвращает каталог публичных каналов.
+// Параметры: q – строка поиска по названию и описанию, limit и offset – постраничная выдача.
+func ListChannelsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := jwt.ExtractJWT(w, r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = defaultDirectoryLimit
+	}
+	if limit > maxDirectoryLimit {
+		limit = maxDirectoryLimit
+	}
+	offset, err := strconv.Atoi(r.URL.Query().Get("offset"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	channels, err := manager.ListPublicChannels(r.URL.Query().Get("q"), userID, limit, offset)
+	if err != nil {
+		http.Error(w, "cannot list channels", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"channels": channels,
+	})
+}
+
+// ChannelPreviewHandler возвращает описание публичного канала и его последние сообщения.
+// Подписка для предпросмотра не требуется.
+func ChannelPreviewHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := jwt.ExtractJWT(w, r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	chatID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "invalid chat id", http.StatusBadRequest)
+		return
+	}
+	if !manager.IsPublicChannel(uint(chatID)) {
+		http.Error(w, "channel not found", http.StatusNotFound)
+		return
+	}
+
+	msgs, err := manager.GetRecentMessages(uint(chatID), previewMessages)
+	if err != nil {
+		http.Error(w, "cannot get messages", http.StatusInternalServerError)
+		return
+	}
+	messagesJSON, err := formatMessages(msgs, userID)
+	if err != nil {
+		http.Error(w, "cannot get messages", http.StatusInternalServerError)
+		return
+	}
+
+	chat := manager.GetChatByID(uint(chatID))
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":          chat.ID,
+		"title":       chat.Title,
+		"description": chat.Description,
+		"subscribers": manager.SubscriberCount(chat.ID),
+		"subscribed":  manager.IsChatMember(chat.ID, userID),
+		"messages":    messagesJSON,
+	})
+}
+
+// SubscribeHandler подписывает (POST) или отписывает (DELETE) пользователя от публичного канала.
+func SubscribeHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := jwt.ExtractJWT(w, r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	chatID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "invalid chat id", http.StatusBadRequest)
+		return
+	}
+
+	var subscribers int
+	if r.Method == http.MethodDelete {
+		subscribers, err = ws.WSmanager.Unsubscribe(userID, uint(chatID))
+	} else {
+		subscribers, err = ws.WSmanager.Subscribe(userID, uint(chatID))
+	}
+	if err != nil {
+		writeGroupError(w, err, "cannot update subscription")
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"chatId":      chatID,
+		"subscribed":  r.Method != http.MethodDelete,
+		"subscribers": subscribers,
+	})
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/gorilla/mux"
 	"net/http"
 	"orion/server/data/manager"
+	"orion/server/data/models"
 	"orion/server/services/jwt"
 	"orion/server/services/minio"
 	"orion/server/services/ws"
@@ -17,6 +18,23 @@ func RegisterRoutes(r *mux.Router) {
 	r.HandleFunc("/api/chats", GetChatsHandler).Methods("GET")
 	r.HandleFunc("/api/chat", CreateChatHandler).Methods("POST")
 	r.HandleFunc("/api/messages", GetChatMessagesHandler).Methods("GET")
+	r.HandleFunc("/api/messages/{id:[0-9]+}/thread", GetThreadHandler).Methods("GET")
+	r.HandleFunc("/api/chats/{id:[0-9]+}/pins", GetPinnedMessagesHandler).Methods("GET")
+	r.HandleFunc("/api/groups", CreateGroupHandler).Methods("POST")
+	r.HandleFunc("/api/chats/{id:[0-9]+}/members", AddMembersHandler).Methods("POST")
+	r.HandleFunc("/api/chats/{id:[0-9]+}/members/{userId:[0-9]+}", RemoveMemberHandler).Methods("DELETE")
+	r.HandleFunc("/api/chats/{id:[0-9]+}/leave", LeaveGroupHandler).Methods("POST")
+	r.HandleFunc("/api/chats/{id:[0-9]+}/roles", GetRolesHandler).Methods("GET")
+	r.HandleFunc("/api/chats/{id:[0-9]+}/roles", CreateRoleHandler).Methods("POST")
+	r.HandleFunc("/api/chats/{id:[0-9]+}/roles/{roleId:[0-9]+}", UpdateRoleHandler).Methods("PUT")
+	r.HandleFunc("/api/chats/{id:[0-9]+}/roles/{roleId:[0-9]+}", DeleteRoleHandler).Methods("DELETE")
+	r.HandleFunc("/api/chats/{id:[0-9]+}/members/{userId:[0-9]+}/roles", SetMemberRolesHandler).Methods("PUT")
+	r.HandleFunc("/api/chats/{id:[0-9]+}/settings", UpdateChannelSettingsHandler).Methods("PUT")
+	r.HandleFunc("/api/chats/{id:[0-9]+}/analytics", GetAnalyticsHandler).Methods("GET")
+	r.HandleFunc("/api/channels", ListChannelsHandler).Methods("GET")
+	r.HandleFunc("/api/channels", CreateChannelHandler).Methods("POST")
+	r.HandleFunc("/api/channels/{id:[0-9]+}/preview", ChannelPreviewHandler).Methods("GET")
+	r.HandleFunc("/api/channels/{id:[0-9]+}/subscribe", SubscribeHandler).Methods("POST", "DELETE")
 }
 
 // GetChatsHandler возвращает список чатов и информацию о пользователе.
@@ -36,61 +54,106 @@ func GetChatsHandler(w http.ResponseWriter, r *http.Request) {
 	chatsJSON := make([]map[string]interface{}, 0)
 	user := manager.GetUserByID(userID)
 
-	for _, chat := range chats {
-		users, _ := manager.GetUsersInChat(chat.ID)
+	// Присутствие участников личных чатов запрашивается одним вызовом на весь список.
+	// Подписчики публичного канала не раскрываются: для него возвращается только их количество.
+	chatUsers := make([][]models.User, len(chats))
+	memberCounts := make([]int, len(chats))
+	var directIDs []uint
+	for i, chat := range chats {
+		if chat.IsGroup && !chat.IsPrivate {
+			memberCounts[i] = manager.SubscriberCount(chat.ID)
+			continue
+		}
+		chatUsers[i], _ = manager.GetUsersInChat(chat.ID)
+		memberCounts[i] = len(chatUsers[i])
+		if !chat.IsGroup {
+			for _, u := range chatUsers[i] {
+				directIDs = append(directIDs, u.ID)
+			}
+		}
+	}
+	online := ws.WSmanager.VisibleOnlineSet(userID, directIDs)
+
+	// Хэши фото собеседников и аватаров групп; фото загружаются из MinIO одним пакетом после цикла
+	photoHashes := make([]string, len(chats))
+	for i, chat := range chats {
+		users := chatUsers[i]
 		var chatName string
-		var profilePicture string
 		var otherUserID uint
 		var lastOnline time.Time
 		var isOnline bool
 
-		// Формируем расширенную информацию о пользователях
-		userList := make([]map[string]interface{}, 0)
+		// Формируем расширенную информацию о пользователях.
+		// Присутствие и фото собеседника нужны только в личных чатах: в группе и канале
+		// их пришлось бы запрашивать для каждого участника.
+		userList := make([]map[string]interface{}, 0, len(users))
 		for _, user := range users {
 			userData := map[string]interface{}{
 				"id":          user.ID,
 				"username":    user.UserName,
-				"is_online":   ws.WSmanager.Connections[user.ID] != nil,
 				"last_online": user.LastOnline.Format(time.RFC3339),
 			}
 
-			if user.ID != userID {
-				chatName = user.UserName
-				profilePicture = minio.GetPhoto(user.ProfilePicture)
-				otherUserID = user.ID
-				lastOnline = user.LastOnline
-				isOnline = ws.WSmanager.Connections[user.ID] != nil
+			if !chat.IsGroup {
+				userData["is_online"] = online[user.ID]
+				if user.ID != userID {
+					chatName = user.UserName
+					photoHashes[i] = user.ProfilePicture
+					otherUserID = user.ID
+					lastOnline = user.LastOnline
+					isOnline = online[user.ID]
+				}
 			}
 
 			userList = append(userList, userData)
 		}
 
+		// У группы нет «собеседника»: показываем её название
+		if chat.IsGroup {
+			chatName = chat.Title
+			photoHashes[i] = chat.Avatar
+			lastOnline = chat.UpdatedAt
+		}
+
 		chatJSON := map[string]interface{}{
 			"id":              chat.ID,
 			"name":            chatName,
+			"is_group":        chat.IsGroup,
+			"description":     chat.Description,
+			"member_count":    memberCounts[i],
 			"readed":          manager.IfReadedChat(chat.ID, userID),
 			"is_private":      chat.IsPrivate,
-			"profile_picture": profilePicture,
+			"profile_picture": "",
 			"users":           userList,
 			"other_user_id":   otherUserID,
 			"last_activity":   lastOnline.Format(time.RFC3339),
 			"is_online":       isOnline,
 			"unread_count":    manager.GetUnreadCount(chat.ID, userID),
+			"privileges":      manager.GetPrivileges(userID, chat.ID),
 			"Bio":             user.Bio,
 		}
 
 		chatsJSON = append(chatsJSON, chatJSON)
 	}
 
+	photos := minio.GetPhotos(append(photoHashes, user.ProfilePicture))
+	for i, chat := range chats {
+		// У группы без аватара фото нет, а не «none»
+		if !chat.IsGroup || chat.Avatar != "" {
+			chatsJSON[i]["profile_picture"] = photos[photoHashes[i]]
+		}
+	}
+
 	resp := map[string]interface{}{
 		"chats": chatsJSON,
 		"info": map[string]interface{}{
 			"id":             user.ID,
 			"Mail":           user.Mail,
 			"UserName":       user.UserName,
+			"DisplayName":    user.DisplayName,
 			"IsBlocked":      user.IsBlocked,
 			"LastOnline":     user.LastOnline.Format(time.RFC3339),
-			"ProfilePicture": minio.GetPhoto(user.ProfilePicture),
+			"ProfilePicture": photos[user.ProfilePicture],
 			"Bio":            user.Bio,
 		},
 	}
@@ -124,13 +187,27 @@ func CreateChatHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(chat)
 }
 
-// GetChatMessagesHandler возвращает все сообщения в чате.
+// defaultMessagesLimit и maxMessagesLimit – размер страницы истории чата по умолчанию и максимальный.
+const (
+	defaultMessagesLimit = 50
+	maxMessagesLimit     = 200
+)
+
+// GetChatMessagesHandler возвращает страницу сообщений чата.
+//
+// Параметры запроса:
+//   - chatId: идентификатор чата;
+//   - before: вернуть сообщения старше указанного ID (по умолчанию – самые новые);
+//   - after: вернуть сообщения новее указанного ID (имеет приоритет над before);
+//   - limit: размер страницы (по умолчанию 50, не больше 200).
+//
+// Поле hasMore сообщает, есть ли ещё сообщения в направлении выборки.
 func GetChatMessagesHandler(w http.ResponseWriter, r *http.Request) {
-	//userID, err := jwt.ExtractJWT(w, r)
-	//if err != nil {
-	//	http.Error(w, "Unauthorized", http.StatusUnauthorized)
-	//	return
-	//}
+	userID, err := jwt.ExtractJWT(w, r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
 	chatIDStr := r.URL.Query().Get("chatId")
 	chatID, err := strconv.Atoi(chatIDStr)
 
@@ -140,29 +217,226 @@ func GetChatMessagesHandler(w http.ResponseWriter, r *http.Request) {
 		json.NewEncoder(w).Encode(map[string]interface{}{
 			"chatId":   chatID,
 			"messages": messagesJSON,
+			"hasMore":  false,
 		})
 	} else {
+		if !manager.HasChannelPriv(userID, uint(chatID), manager.PrivReading) {
+			http.Error(w, "chat not found", http.StatusNotFound)
+			return
+		}
+
+		var before, after uint64
+		if v := r.URL.Query().Get("before"); v != "" {
+			if before, err = strconv.ParseUint(v, 10, 64); err != nil {
+				http.Error(w, "invalid before", http.StatusBadRequest)
+				return
+			}
+		}
+		if v := r.URL.Query().Get("after"); v != "" {
+			if after, err = strconv.ParseUint(v, 10, 64); err != nil {
+				http.Error(w, "invalid after", http.StatusBadRequest)
+				return
+			}
+		}
+		limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+		if err != nil || limit <= 0 {
+			limit = defaultMessagesLimit
+		}
+		if limit > maxMessagesLimit {
+			limit = maxMessagesLimit
+		}
 
-		msgs, err := manager.GetChanMassages(uint(chatID))
+		msgs, hasMore, err := manager.GetChanMassages(uint(chatID), userID, uint(before), uint(after), limit)
 		if err != nil {
 			http.Error(w, "cannot get messages", http.StatusInternalServerError)
 			return
 		}
 
-		messagesJSON := []map[string]interface{}{}
-		for _, m := range msgs {
-			messagesJSON = append(messagesJSON, map[string]interface{}{
-				"id":        m.ID,
-				"from":      m.UserID,
-				"message":   m.Content,
-				"timestamp": m.Timestamp.Format(time.RFC3339),
-				"readed":    m.Readed,
-			})
+		messagesJSON, err := formatMessages(msgs, userID)
+		if err != nil {
+			http.Error(w, "cannot get messages", http.StatusInternalServerError)
+			return
 		}
 		json.NewEncoder(w).Encode(map[string]interface{}{
 			"chatId":   chatID,
 			"messages": messagesJSON,
+			"hasMore":  hasMore,
 		})
 	}
 
 }
+
+// GetThreadHandler возвращает сообщение и все ответы на него в порядке отправки.
+func GetThreadHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := jwt.ExtractJWT(w, r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	rootID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "invalid message id", http.StatusBadRequest)
+		return
+	}
+
+	root, err := manager.GetMessageByID(uint(rootID))
+	if err != nil || !manager.HasChannelPriv(userID, root.ChannelID, manager.PrivReading) {
+		http.Error(w, "message not found", http.StatusNotFound)
+		return
+	}
+	replies, err := manager.GetThread(root.ID, userID)
+	if err != nil {
+		http.Error(w, "cannot get thread", http.StatusInternalServerError)
+		return
+	}
+
+	rootJSON, err := formatMessages([]models.Message{root}, userID)
+	if err != nil {
+		http.Error(w, "cannot get thread", http.StatusInternalServerError)
+		return
+	}
+	repliesJSON, err := formatMessages(replies, userID)
+	if err != nil {
+		http.Error(w, "cannot get thread", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"chatId":  root.ChannelID,
+		"root":    rootJSON[0],
+		"replies": repliesJSON,
+	})
+}
+
+// GetPinnedMessagesHandler возвращает закреплённые сообщения чата, начиная с последнего закреплённого.
+func GetPinnedMessagesHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := jwt.ExtractJWT(w, r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	chatID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "invalid chat id", http.StatusBadRequest)
+		return
+	}
+	if !manager.HasChannelPriv(userID, uint(chatID), manager.PrivReading) {
+		http.Error(w, "chat not found", http.StatusNotFound)
+		return
+	}
+
+	msgs, err := manager.GetPinnedMessages(uint(chatID))
+	if err != nil {
+		http.Error(w, "cannot get pinned messages", http.StatusInternalServerError)
+		return
+	}
+	messagesJSON, err := formatMessages(msgs, userID)
+	if err != nil {
+		http.Error(w, "cannot get pinned messages", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"chatId":   chatID,
+		"messages": messagesJSON,
+	})
+}
+
+// quotePreviewLen – максимальная длина текста цитаты родительского сообщения (в символах).
+const quotePreviewLen = 100
+
+// formatMessages преобразует сообщения в формат ответа API: добавляет реакции,
+// количество ответов и краткую цитату сообщения, на которое дан ответ.
+func formatMessages(msgs []models.Message, userID uint) ([]map[string]interface{}, error) {
+	ids := make([]uint, 0, len(msgs))
+	var parentIDs []uint
+	for _, m := range msgs {
+		ids = append(ids, m.ID)
+		if m.ReplyToID != nil {
+			parentIDs = append(parentIDs, *m.ReplyToID)
+		}
+	}
+	reactions, err := manager.GetReactionCounts(ids, userID)
+	if err != nil {
+		return nil, err
+	}
+	replyCounts, err := manager.GetReplyCounts(ids, userID)
+	if err != nil {
+		return nil, err
+	}
+	parents, err := manager.GetMessagesByIDs(parentIDs)
+	if err != nil {
+		return nil, err
+	}
+	hiddenParents, err := manager.GetHiddenMessageIDs(userID, parentIDs)
+	if err != nil {
+		return nil, err
+	}
+	cursors := make(map[uint]map[uint]uint)
+	for _, m := range msgs {
+		if _, ok := cursors[m.ChannelID]; ok {
+			continue
+		}
+		if cursors[m.ChannelID], err = manager.GetChannelCursors(m.ChannelID); err != nil {
+			return nil, err
+		}
+	}
+
+	messagesJSON := []map[string]interface{}{}
+	for _, m := range msgs {
+		// Сообщение прочитано участниками (кроме автора), чей курсор не меньше его ID
+		readCount := 0
+		for readerID, lastReadID := range cursors[m.ChannelID] {
+			if readerID != m.UserID && lastReadID >= m.ID {
+				readCount++
+			}
+		}
+		messageReactions := reactions[m.ID]
+		if messageReactions == nil {
+			messageReactions = []manager.ReactionCount{}
+		}
+		messageJSON := map[string]interface{}{
+			"id":         m.ID,
+			"seq":        m.Seq,
+			"from":       m.UserID,
+			"message":    m.Content,
+			"timestamp":  m.Timestamp.Format(time.RFC3339),
+			"readed":     readCount > 0,
+			"readCount":  readCount,
+			"edited":     m.Edited,
+			"reactions":  messageReactions,
+			"replyCount": replyCounts[m.ID],
+			"system":     m.System,
+		}
+		if m.ForwardedFromUserID != nil && m.ForwardedFromChatID != nil {
+			messageJSON["forwardedFrom"] = map[string]interface{}{
+				"userId": *m.ForwardedFromUserID,
+				"chatId": *m.ForwardedFromChatID,
+			}
+		}
+		if m.ReplyToID != nil {
+			if parent, ok := parents[*m.ReplyToID]; ok {
+				messageJSON["replyTo"] = quotePreview(parent, hiddenParents[parent.ID])
+			}
+		}
+		messagesJSON = append(messagesJSON, messageJSON)
+	}
+	return messagesJSON, nil
+}
+
+// quotePreview формирует краткую цитату сообщения. Текст удалённого для всех или скрытого
+// пользователем (hidden) сообщения не раскрывается: оба случая выглядят как удалённое сообщение.
+func quotePreview(m models.Message, hidden bool) map[string]interface{} {
+	deleted := m.DeletedAt.Valid || hidden
+	text := ""
+	if !deleted {
+		text = m.Content
+		if runes := []rune(text); len(runes) > quotePreviewLen {
+			text = string(runes[:quotePreviewLen]) + "…"
+		}
+	}
+	return map[string]interface{}{
+		"id":      m.ID,
+		"from":    m.UserID,
+		"message": text,
+		"deleted": deleted,
+	}
+}
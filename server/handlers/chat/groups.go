@@ -0,0 +1,139 @@
+package chat
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gorilla/mux"
+	"net/http"
+	"orion/server/services/jwt"
+	"orion/server/services/ws"
+	"strconv"
+)
+
+// CreateGroupHandler создаёт групповой чат.
+// Тело запроса: {"title": "Инциденты", "description": "...", "members": [2, 3]}.
+func CreateGroupHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := jwt.ExtractJWT(w, r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	type body struct {
+		Title       string `json:"title"`
+		Description string `json:"description"`
+		Members     []uint `json:"members"`
+	}
+	var b body
+	if err := json.NewDecoder(r.Body).Decode(&b); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+
+	chat, err := ws.WSmanager.CreateGroup(userID, b.Title, b.Description, b.Members, false)
+	if err != nil {
+		writeGroupError(w, err, "cannot create group")
+		return
+	}
+
+	members := make([]uint, 0, len(chat.Users))
+	for _, u := range chat.Users {
+		members = append(members, u.ID)
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":          chat.ID,
+		"title":       chat.Title,
+		"description": chat.Description,
+		"is_group":    chat.IsGroup,
+		"members":     members,
+	})
+}
+
+// AddMembersHandler добавляет пользователей в группу. Тело запроса: {"userIds": [4, 5]}.
+func AddMembersHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := jwt.ExtractJWT(w, r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	chatID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "invalid chat id", http.StatusBadRequest)
+		return
+	}
+
+	type body struct {
+		UserIDs []uint `json:"userIds"`
+	}
+	var b body
+	if err := json.NewDecoder(r.Body).Decode(&b); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+
+	added, err := ws.WSmanager.AddMembers(userID, uint(chatID), b.UserIDs)
+	if err != nil {
+		writeGroupError(w, err, "cannot add members")
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"chatId": chatID,
+		"added":  added,
+	})
+}
+
+// RemoveMemberHandler исключает пользователя из группы.
+func RemoveMemberHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := jwt.ExtractJWT(w, r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	chatID, err1 := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	memberID, err2 := strconv.ParseUint(mux.Vars(r)["userId"], 10, 64)
+	if err1 != nil || err2 != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	if err := ws.WSmanager.RemoveMember(userID, uint(chatID), uint(memberID)); err != nil {
+		writeGroupError(w, err, "cannot remove member")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// LeaveGroupHandler выводит текущего пользователя из группы. Владелец выйти не может (403).
+func LeaveGroupHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := jwt.ExtractJWT(w, r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	chatID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "invalid chat id", http.StatusBadRequest)
+		return
+	}
+
+	if err := ws.WSmanager.RemoveMember(userID, uint(chatID), userID); err != nil {
+		writeGroupError(w, err, "cannot leave group")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeGroupError преобразует ошибку операции с группой в HTTP-ответ.
+func writeGroupError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, ws.ErrInvalidQuery):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, ws.ErrChatNotFound):
+		http.Error(w, "chat not found", http.StatusNotFound)
+	case errors.Is(err, ws.ErrForbidden), errors.Is(err, ws.ErrBlocked):
+		http.Error(w, "forbidden", http.StatusForbidden)
+	default:
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
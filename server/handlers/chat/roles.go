@@ -0,0 +1,159 @@
+package chat
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gorilla/mux"
+	"net/http"
+	"orion/server/services/jwt"
+	"orion/server/services/ws"
+	"strconv"
+)
+
+// roleBody – тело запросов создания и изменения роли.
+//
+// Пример: {"name": "Модератор", "privileges": {"deletion_priv": true, "pinning_messages_priv": true}}
+type roleBody struct {
+	Name       string          `json:"name"`
+	Privileges map[string]bool `json:"privileges"`
+}
+
+// GetRolesHandler возвращает роли чата с назначенными участниками.
+func GetRolesHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := jwt.ExtractJWT(w, r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	chatID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "invalid chat id", http.StatusBadRequest)
+		return
+	}
+
+	roles, err := ws.WSmanager.Roles(userID, uint(chatID))
+	if err != nil {
+		writeRoleError(w, err, "cannot get roles")
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"chatId": chatID,
+		"roles":  roles,
+	})
+}
+
+// CreateRoleHandler создаёт роль в чате.
+func CreateRoleHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := jwt.ExtractJWT(w, r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	chatID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "invalid chat id", http.StatusBadRequest)
+		return
+	}
+	var b roleBody
+	if err := json.NewDecoder(r.Body).Decode(&b); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+
+	role, err := ws.WSmanager.CreateRole(userID, uint(chatID), b.Name, b.Privileges)
+	if err != nil {
+		writeRoleError(w, err, "cannot create role")
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(role)
+}
+
+// UpdateRoleHandler изменяет название и привилегии роли.
+func UpdateRoleHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := jwt.ExtractJWT(w, r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	chatID, err1 := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	roleID, err2 := strconv.ParseUint(mux.Vars(r)["roleId"], 10, 64)
+	if err1 != nil || err2 != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	var b roleBody
+	if err := json.NewDecoder(r.Body).Decode(&b); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+
+	role, err := ws.WSmanager.UpdateRole(userID, uint(chatID), uint(roleID), b.Name, b.Privileges)
+	if err != nil {
+		writeRoleError(w, err, "cannot update role")
+		return
+	}
+	json.NewEncoder(w).Encode(role)
+}
+
+// DeleteRoleHandler удаляет пользовательскую роль.
+func DeleteRoleHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := jwt.ExtractJWT(w, r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	chatID, err1 := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	roleID, err2 := strconv.ParseUint(mux.Vars(r)["roleId"], 10, 64)
+	if err1 != nil || err2 != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	if err := ws.WSmanager.DeleteRole(userID, uint(chatID), uint(roleID)); err != nil {
+		writeRoleError(w, err, "cannot delete role")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SetMemberRolesHandler заменяет роли участника чата. Тело запроса: {"roleIds": [3, 5]}.
+func SetMemberRolesHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := jwt.ExtractJWT(w, r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	chatID, err1 := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	memberID, err2 := strconv.ParseUint(mux.Vars(r)["userId"], 10, 64)
+	if err1 != nil || err2 != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	type body struct {
+		RoleIDs []uint `json:"roleIds"`
+	}
+	var b body
+	if err := json.NewDecoder(r.Body).Decode(&b); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+
+	if err := ws.WSmanager.SetMemberRoles(userID, uint(chatID), uint(memberID), b.RoleIDs); err != nil {
+		writeRoleError(w, err, "cannot set roles")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeRoleError преобразует ошибку операции с ролями в HTTP-ответ.
+func writeRoleError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, ws.ErrRoleNotFound):
+		http.Error(w, "role not found", http.StatusNotFound)
+	case errors.Is(err, ws.ErrNotMember):
+		http.Error(w, "member not found", http.StatusNotFound)
+	default:
+		writeGroupError(w, err, fallback)
+	}
+}
@@ -0,0 +1,86 @@
+package chat
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gorilla/mux"
+	"net/http"
+	"orion/server/data/manager"
+	"orion/server/services/jwt"
+	"orion/server/services/minio"
+	"orion/server/services/ws"
+	"strconv"
+)
+
+// writeImageError преобразует ошибку загрузки изображения в HTTP-ответ.
+func writeImageError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, minio.ErrImageTooLarge):
+		http.Error(w, "image is too large", http.StatusRequestEntityTooLarge)
+	case errors.Is(err, minio.ErrInvalidImage):
+		http.Error(w, "invalid image data", http.StatusBadRequest)
+	default:
+		http.Error(w, "upload error", http.StatusInternalServerError)
+	}
+}
+
+// UpdateChannelSettingsHandler изменяет название, описание и аватар группы или канала.
+// Все поля необязательны; imageData передаётся как data URL, как при загрузке фото профиля.
+//
+// Тело запроса: {"title": "Инциденты", "description": "...", "imageData": "data:image/jpeg;base64,..."}.
+func UpdateChannelSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := jwt.ExtractJWT(w, r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	chatID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "invalid chat id", http.StatusBadRequest)
+		return
+	}
+
+	type body struct {
+		Title       *string `json:"title"`
+		Description *string `json:"description"`
+		ImageData   *string `json:"imageData"`
+	}
+	var b body
+	r.Body = http.MaxBytesReader(w, r.Body, minio.MaxDataURLSize)
+	if err := json.NewDecoder(r.Body).Decode(&b); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+
+	settings := ws.ChannelSettings{Title: b.Title, Description: b.Description}
+	if b.ImageData != nil {
+		// Права проверяются до загрузки, чтобы не сохранять в MinIO чужие изображения
+		if !manager.HasChannelPriv(userID, uint(chatID), manager.PrivChannelEdit) {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		imageHex, _, err := minio.UploadDataURL(r.Context(), *b.ImageData)
+		if err != nil {
+			writeImageError(w, err)
+			return
+		}
+		settings.Avatar = &imageHex
+	}
+
+	chat, err := ws.WSmanager.UpdateChannelSettings(userID, uint(chatID), settings)
+	if err != nil {
+		writeGroupError(w, err, "cannot update settings")
+		return
+	}
+
+	avatar := ""
+	if chat.Avatar != "" {
+		avatar = minio.GetPhoto(chat.Avatar)
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":          chat.ID,
+		"title":       chat.Title,
+		"description": chat.Description,
+		"avatar":      avatar,
+	})
+}
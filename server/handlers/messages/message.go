@@ -1,19 +1,31 @@
 package messages
 
 import (
+	"encoding/json"
+	"errors"
 	"github.com/gorilla/mux"
 	"net/http"
 	"orion/server/data/manager"
 	"orion/server/services/jwt"
+	"orion/server/services/ws"
 	"strconv"
+	"time"
 )
 
 // RegisterRoutes регистрирует маршруты чата на переданном роутере
 func RegisterRoutes(r *mux.Router) {
 	r.HandleFunc("/api/messages/read", MarkMessagesReadHandler).Methods("POST", "PUT")
+	r.HandleFunc("/api/messages/forward", ForwardMessagesHandler).Methods("POST")
+	r.HandleFunc("/api/messages/search", SearchMessagesHandler).Methods("GET")
+	r.HandleFunc("/api/messages/{id:[0-9]+}", EditMessageHandler).Methods("PUT")
+	r.HandleFunc("/api/messages/{id:[0-9]+}", DeleteMessageHandler).Methods("DELETE")
+	r.HandleFunc("/api/messages/{id:[0-9]+}/history", GetMessageHistoryHandler).Methods("GET")
+	r.HandleFunc("/api/messages/{id:[0-9]+}/readers", GetMessageReadersHandler).Methods("GET")
+	r.HandleFunc("/api/messages/{id:[0-9]+}/pin", PinMessageHandler).Methods("PUT", "DELETE")
 }
 
-// MarkMessagesReadHandler помечает сообщения как прочитанные.
+// MarkMessagesReadHandler помечает сообщения как прочитанные и уведомляет участников чата.
+// Необязательный параметр lastReadId ограничивает отметку сообщениями с ID не больше него.
 func MarkMessagesReadHandler(w http.ResponseWriter, r *http.Request) {
 	userID, err := jwt.ExtractJWT(w, r)
 	if err != nil {
@@ -22,10 +34,248 @@ func MarkMessagesReadHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	chatIDStr := r.URL.Query().Get("chatId")
 	chatID, err := strconv.Atoi(chatIDStr)
-	if err != nil {
+	if err != nil || chatID <= 0 {
 		http.Error(w, "invalid chatId", http.StatusBadRequest)
 		return
 	}
-	manager.ReadMessages(float64(chatID), strconv.Itoa(int(userID)))
+	var lastReadID uint64
+	if v := r.URL.Query().Get("lastReadId"); v != "" {
+		lastReadID, err = strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			http.Error(w, "invalid lastReadId", http.StatusBadRequest)
+			return
+		}
+	}
+
+	_, err = ws.WSmanager.MarkRead(userID, uint(chatID), uint(lastReadID))
+	if errors.Is(err, ws.ErrNotMember) {
+		http.Error(w, "chat not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, ws.ErrForbidden) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		http.Error(w, "cannot mark messages read", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// EditMessageHandler изменяет текст сообщения. Тело запроса: {"message": "новый текст"}.
+func EditMessageHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := jwt.ExtractJWT(w, r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	messageID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "invalid message id", http.StatusBadRequest)
+		return
+	}
+
+	type body struct {
+		Message string `json:"message"`
+	}
+	var b body
+	if err := json.NewDecoder(r.Body).Decode(&b); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+
+	msg, err := ws.WSmanager.EditMessage(userID, uint(messageID), b.Message)
+	switch {
+	case errors.Is(err, ws.ErrMessageNotFound):
+		http.Error(w, "message not found", http.StatusNotFound)
+		return
+	case errors.Is(err, ws.ErrForbidden):
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	case errors.Is(err, ws.ErrEmptyMessage):
+		http.Error(w, "empty message", http.StatusBadRequest)
+		return
+	case err != nil:
+		http.Error(w, "cannot edit message", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":      msg.ID,
+		"chatId":  msg.ChannelID,
+		"message": msg.Content,
+		"edited":  msg.Edited,
+	})
+}
+
+// DeleteMessageHandler удаляет сообщение. Параметр forEveryone=true удаляет сообщение у всех
+// участников чата, иначе оно скрывается только у текущего пользователя.
+func DeleteMessageHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := jwt.ExtractJWT(w, r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	messageID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "invalid message id", http.StatusBadRequest)
+		return
+	}
+	forEveryone := r.URL.Query().Get("forEveryone") == "true"
+
+	err = ws.WSmanager.DeleteMessage(userID, uint(messageID), forEveryone)
+	switch {
+	case errors.Is(err, ws.ErrMessageNotFound):
+		http.Error(w, "message not found", http.StatusNotFound)
+		return
+	case errors.Is(err, ws.ErrForbidden):
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	case err != nil:
+		http.Error(w, "cannot delete message", http.StatusInternalServerError)
+		return
+	}
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// ForwardMessagesHandler пересылает сообщения в другие чаты пользователя.
+// Тело запроса: {"messageIds": [118, 120], "chatIds": [3, 7]}.
+func ForwardMessagesHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := jwt.ExtractJWT(w, r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var b ws.ForwardMessages
+	if err := json.NewDecoder(r.Body).Decode(&b); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+
+	acks, err := ws.WSmanager.ForwardMessages(userID, b.MessageIds, b.ChatIds)
+	switch {
+	case errors.Is(err, ws.ErrInvalidQuery):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, ws.ErrMessageNotFound):
+		http.Error(w, "message not found", http.StatusNotFound)
+		return
+	case errors.Is(err, ws.ErrForbidden), errors.Is(err, ws.ErrBlocked):
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	case err != nil:
+		http.Error(w, "cannot forward messages", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"messages": acks,
+	})
+}
+
+// GetMessageHistoryHandler возвращает историю изменений сообщения, от старых версий к новым.
+func GetMessageHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := jwt.ExtractJWT(w, r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	messageID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "invalid message id", http.StatusBadRequest)
+		return
+	}
+
+	msg, err := manager.GetMessageByID(uint(messageID))
+	if err != nil || !manager.HasChannelPriv(userID, msg.ChannelID, manager.PrivReading) {
+		http.Error(w, "message not found", http.StatusNotFound)
+		return
+	}
+	edits, err := manager.GetMessageEdits(msg.ID)
+	if err != nil {
+		http.Error(w, "cannot get history", http.StatusInternalServerError)
+		return
+	}
+
+	editsJSON := []map[string]interface{}{}
+	for _, e := range edits {
+		editsJSON = append(editsJSON, map[string]interface{}{
+			"editorId":        e.EditorID,
+			"previousContent": e.PreviousContent,
+			"editedAt":        e.EditedAt.Format(time.RFC3339),
+		})
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":      msg.ID,
+		"message": msg.Content,
+		"edited":  msg.Edited,
+		"edits":   editsJSON,
+	})
+}
+
+// PinMessageHandler закрепляет (PUT) или открепляет (DELETE) сообщение в его чате.
+func PinMessageHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := jwt.ExtractJWT(w, r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	messageID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "invalid message id", http.StatusBadRequest)
+		return
+	}
+
+	result, err := ws.WSmanager.SetPinned(userID, uint(messageID), r.Method == http.MethodPut)
+	switch {
+	case errors.Is(err, ws.ErrMessageNotFound):
+		http.Error(w, "message not found", http.StatusNotFound)
+		return
+	case errors.Is(err, ws.ErrForbidden):
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	case err != nil:
+		http.Error(w, "cannot update pins", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(result)
+}
+
+// GetMessageReadersHandler возвращает участников чата, прочитавших сообщение (кроме его автора).
+func GetMessageReadersHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := jwt.ExtractJWT(w, r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	messageID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "invalid message id", http.StatusBadRequest)
+		return
+	}
+
+	msg, err := manager.GetMessageByID(uint(messageID))
+	if err != nil || !manager.HasChannelPriv(userID, msg.ChannelID, manager.PrivReading) {
+		http.Error(w, "message not found", http.StatusNotFound)
+		return
+	}
+	readers, err := manager.GetMessageReaders(msg)
+	if err != nil {
+		http.Error(w, "cannot get readers", http.StatusInternalServerError)
+		return
+	}
+
+	readersJSON := []map[string]interface{}{}
+	for _, u := range readers {
+		readersJSON = append(readersJSON, map[string]interface{}{
+			"id":       u.ID,
+			"username": u.UserName,
+		})
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":      msg.ID,
+		"readers": readersJSON,
+	})
+}
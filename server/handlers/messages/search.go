@@ -0,0 +1,114 @@
+package messages
+
+import (
+	"encoding/json"
+	"net/http"
+	"orion/server/data/manager"
+	"orion/server/services/jwt"
+	"strconv"
+	"time"
+)
+
+const (
+	// defaultSearchLimit – количество результатов поиска на странице по умолчанию.
+	defaultSearchLimit = 20
+	// maxSearchLimit – максимальное количество результатов поиска на странице.
+	maxSearchLimit = 100
+)
+
+// SearchMessagesHandler ищет сообщения по тексту в чатах, которые пользователь может читать.
+//
+// Параметры запроса:
+//   - q: поисковый запрос (обязательный);
+//   - chatId: искать только в указанном чате;
+//   - userId: искать только сообщения указанного автора;
+//   - from, to: даты в формате YYYY-MM-DD (UTC) включительно;
+//   - before: курсор – ID сообщения, с которого продолжить выдачу (nextBefore предыдущей страницы);
+//   - limit: размер страницы (по умолчанию 20, не больше 100).
+func SearchMessagesHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := jwt.ExtractJWT(w, r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	query := r.URL.Query()
+	filter := manager.MessageSearchFilter{Query: query.Get("q")}
+	if filter.Query == "" {
+		http.Error(w, "q is required", http.StatusBadRequest)
+		return
+	}
+
+	uintParams := []struct {
+		name string
+		dst  *uint
+	}{
+		{"chatId", &filter.ChatID},
+		{"userId", &filter.AuthorID},
+		{"before", &filter.Before},
+	}
+	for _, p := range uintParams {
+		v := query.Get(p.name)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			http.Error(w, "invalid "+p.name, http.StatusBadRequest)
+			return
+		}
+		*p.dst = uint(n)
+	}
+	if v := query.Get("from"); v != "" {
+		day, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			http.Error(w, "invalid from", http.StatusBadRequest)
+			return
+		}
+		filter.From = day
+	}
+	if v := query.Get("to"); v != "" {
+		day, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			http.Error(w, "invalid to", http.StatusBadRequest)
+			return
+		}
+		filter.To = day.AddDate(0, 0, 1)
+	}
+	filter.Limit, err = strconv.Atoi(query.Get("limit"))
+	if err != nil || filter.Limit <= 0 {
+		filter.Limit = defaultSearchLimit
+	}
+	if filter.Limit > maxSearchLimit {
+		filter.Limit = maxSearchLimit
+	}
+
+	// Искать можно только в чатах, где у пользователя есть право чтения
+	var chatIDs []uint
+	if filter.ChatID != 0 {
+		if !manager.HasChannelPriv(userID, filter.ChatID, manager.PrivReading) {
+			http.Error(w, "chat not found", http.StatusNotFound)
+			return
+		}
+		chatIDs = []uint{filter.ChatID}
+	} else {
+		if chatIDs, err = manager.ReadableChannelIDs(userID); err != nil {
+			http.Error(w, "cannot search messages", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	hits, hasMore, err := manager.SearchMessages(userID, chatIDs, filter)
+	if err != nil {
+		http.Error(w, "cannot search messages", http.StatusInternalServerError)
+		return
+	}
+	var nextBefore uint
+	if hasMore {
+		nextBefore = hits[len(hits)-1].ID
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"results":    hits,
+		"hasMore":    hasMore,
+		"nextBefore": nextBefore,
+	})
+}
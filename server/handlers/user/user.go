@@ -1,11 +1,9 @@
 package user
 
 import (
-	"context"
-	"crypto/md5"
 	"encoding/base64"
-	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"github.com/gorilla/mux"
 	"log"
 	"net/http"
@@ -26,10 +24,23 @@ func RegisterRoutes(r *mux.Router) {
 	r.HandleFunc("/api/block-status", CheckUserBlockedHandler).Methods("GET")
 	r.HandleFunc("/api/mutual-block", CheckMutualBlockHandler).Methods("GET")
 	r.HandleFunc("/api/online-status", OnlineStatusHandler).Methods("GET")
+	r.HandleFunc("/api/presence", PresenceHandler).Methods("GET")
 	r.HandleFunc("/api/users", GetUsersHandler).Methods("GET")
 }
 
-// GetUsersHandler возвращает пользователей по подстроке имени.
+const (
+	// defaultUsersLimit – количество пользователей на странице поиска по умолчанию.
+	defaultUsersLimit = 20
+	// maxUsersLimit – максимальное количество пользователей на странице поиска.
+	maxUsersLimit = 50
+)
+
+// GetUsersHandler ищет пользователей по имени пользователя и отображаемому имени.
+//
+// Параметры запроса:
+//   - username: строка поиска (без учёта регистра, допускаются опечатки);
+//   - limit: размер страницы (по умолчанию 20, не больше 50);
+//   - cursor: nextCursor предыдущей страницы.
 func GetUsersHandler(w http.ResponseWriter, r *http.Request) {
 	userID, err := jwt.ExtractJWT(w, r)
 	if err != nil {
@@ -37,21 +48,81 @@ func GetUsersHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	username := r.URL.Query().Get("username")
-	users := manager.SearchByUsername(username)
-	res := []map[string]interface{}{}
-	for _, u := range users {
-		if u.ID == userID {
-			continue // не показываем себя
+	query := r.URL.Query()
+	limit, err := strconv.Atoi(query.Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = defaultUsersLimit
+	}
+	if limit > maxUsersLimit {
+		limit = maxUsersLimit
+	}
+	var after *manager.UserSearchCursor
+	if v := query.Get("cursor"); v != "" {
+		if after, err = decodeUserCursor(v); err != nil {
+			http.Error(w, "invalid cursor", http.StatusBadRequest)
+			return
 		}
+	}
+
+	users, hasMore, err := manager.SearchUsers(userID, query.Get("username"), after, limit)
+	if err != nil {
+		http.Error(w, "cannot search users", http.StatusInternalServerError)
+		return
+	}
+	hashes := make([]string, len(users))
+	for i, u := range users {
+		hashes[i] = u.ProfilePicture
+	}
+	photos := minio.GetPhotos(hashes)
+
+	res := make([]map[string]interface{}, 0, len(users))
+	for _, u := range users {
 		res = append(res, map[string]interface{}{
 			"id":              u.ID,
 			"username":        u.UserName,
-			"profile_picture": minio.GetPhoto(u.ProfilePicture), // Полный URL
-			"chat_id":         manager.GetChatIDForUsers(userID, u.ID),
+			"display_name":    u.DisplayName,
+			"profile_picture": photos[u.ProfilePicture], // Полный URL
+			"chat_id":         u.ChatID,
+			"is_contact":      u.IsContact,
 		})
 	}
-	json.NewEncoder(w).Encode(res)
+	nextCursor := ""
+	if hasMore {
+		last := users[len(users)-1]
+		nextCursor = encodeUserCursor(manager.UserSearchCursor{Score: last.Score, ID: last.ID})
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"users":      res,
+		"hasMore":    hasMore,
+		"nextCursor": nextCursor,
+	})
+}
+
+// encodeUserCursor упаковывает позицию выдачи в непрозрачную для клиента строку.
+func encodeUserCursor(c manager.UserSearchCursor) string {
+	raw := strconv.FormatFloat(c.Score, 'g', -1, 64) + ":" + strconv.FormatUint(uint64(c.ID), 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeUserCursor разбирает строку, полученную из encodeUserCursor.
+func decodeUserCursor(s string) (*manager.UserSearchCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	scorePart, idPart, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return nil, errors.New("malformed cursor")
+	}
+	score, err := strconv.ParseFloat(scorePart, 64)
+	if err != nil {
+		return nil, err
+	}
+	id, err := strconv.ParseUint(idPart, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &manager.UserSearchCursor{Score: score, ID: uint(id)}, nil
 }
 
 // UpdateProfileHandler обновляет профиль пользователя.
@@ -65,13 +136,15 @@ func UpdateProfileHandler(w http.ResponseWriter, r *http.Request) {
 		Mail     string `json:"Mail"`
 		UserName string `json:"UserName"`
 		Biom     string `json:"Biom"`
+
+		DisplayName *string `json:"DisplayName"`
 	}
 	var b body
 	if err := json.NewDecoder(r.Body).Decode(&b); err != nil {
 		http.Error(w, "invalid body", http.StatusBadRequest)
 		return
 	}
-	err = manager.UpdateUser(userID, b.Mail, b.UserName, b.Biom)
+	err = manager.UpdateUser(userID, b.Mail, b.UserName, b.Biom, b.DisplayName)
 	if err != nil {
 		http.Error(w, "update error", http.StatusInternalServerError)
 		return
@@ -91,34 +164,27 @@ func UploadProfilePictureHandler(w http.ResponseWriter, r *http.Request) {
 		ImageData string `json:"imageData"`
 	}
 	var b body
+	r.Body = http.MaxBytesReader(w, r.Body, minio.MaxDataURLSize)
 	if err := json.NewDecoder(r.Body).Decode(&b); err != nil {
 		http.Error(w, "invalid body", http.StatusBadRequest)
 		return
 	}
 
-	parts := strings.SplitN(b.ImageData, ",", 2)
-	if len(parts) != 2 {
-		http.Error(w, "invalid image data", http.StatusBadRequest)
+	imageHex, imageBytes, err := minio.UploadDataURL(r.Context(), b.ImageData)
+	switch {
+	case errors.Is(err, minio.ErrImageTooLarge):
+		http.Error(w, "image is too large", http.StatusRequestEntityTooLarge)
 		return
-	}
-	imageBytes, err := base64.StdEncoding.DecodeString(parts[1])
-	if err != nil {
-		http.Error(w, "base64 decode error", http.StatusBadRequest)
+	case errors.Is(err, minio.ErrInvalidImage):
+		http.Error(w, "invalid image data", http.StatusBadRequest)
 		return
-	}
-	hash := md5.Sum(imageBytes)
-	imageHex := hex.EncodeToString(hash[:])
-	//log.Println(userID)
-	//log.Println(imageHex)
-	manager.AddHexPhoto(userID, imageHex)
-	objectName := imageHex + ".jpg"
-	err = minio.UploadImage(context.Background(), objectName, imageBytes, "image/jpeg")
-	if err != nil {
+	case err != nil:
 		http.Error(w, "upload error", http.StatusInternalServerError)
 		return
 	}
+	manager.AddHexPhoto(userID, imageHex)
 	resp := map[string]string{
-		"ProfilePicture": "data:image/jpeg;base64," + base64.StdEncoding.EncodeToString(imageBytes),
+		"ProfilePicture": minio.DataURL(imageBytes),
 	}
 	json.NewEncoder(w).Encode(resp)
 }
@@ -262,7 +328,7 @@ func OnlineStatusHandler(w http.ResponseWriter, r *http.Request) {
 	//targetUserIDStr := r.URL.Query().Get("userId")
 	//targetUserID, _ := strconv.Atoi(targetUserIDStr)
 
-	isOnline := ws.WSmanager.Connections[userID] != nil
+	isOnline := ws.WSmanager.IsOnline(userID)
 	user := manager.GetUserByID(userID)
 
 	json.NewEncoder(w).Encode(map[string]interface{}{
@@ -270,3 +336,52 @@ func OnlineStatusHandler(w http.ResponseWriter, r *http.Request) {
 		"lastOnline": user.LastOnline,
 	})
 }
+
+// maxPresenceIDs – максимальное количество пользователей в одном запросе присутствия.
+const maxPresenceIDs = 200
+
+// PresenceHandler возвращает присутствие пользователей, перечисленных в параметре ids через запятую.
+//
+// Пример: GET /api/presence?ids=1,2,3
+//
+//	[ { "userId": 1, "online": true, "lastOnline": "2025-01-01T10:00:00Z" }, ... ]
+func PresenceHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := jwt.ExtractJWT(w, r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var ids []uint
+	seen := make(map[uint]bool)
+	for _, part := range strings.Split(r.URL.Query().Get("ids"), ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(part, 10, 64)
+		if err != nil || id == 0 {
+			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+		if !seen[uint(id)] {
+			seen[uint(id)] = true
+			ids = append(ids, uint(id))
+		}
+	}
+	if len(ids) == 0 {
+		http.Error(w, "ids are required", http.StatusBadRequest)
+		return
+	}
+	if len(ids) > maxPresenceIDs {
+		http.Error(w, "too many ids", http.StatusBadRequest)
+		return
+	}
+
+	presence, err := ws.WSmanager.Presence(userID, ids)
+	if err != nil {
+		http.Error(w, "cannot get presence", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(presence)
+}
@@ -0,0 +1,94 @@
+package bus
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"sync"
+)
+
+// Message представляет событие, которое нужно доставить пользователям, подключённым к любой реплике сервера.
+//
+// Поля структуры:
+//   - Origin: идентификатор реплики-отправителя; реплика не доставляет повторно собственные события.
+//   - UserIDs: получатели события.
+//   - Payload: готовое к отправке в сокет JSON-сообщение.
+type Message struct {
+	Origin  string          `json:"o"`
+	UserIDs []uint          `json:"u"`
+	Payload json.RawMessage `json:"p"`
+}
+
+// Handler обрабатывает событие, полученное из шины.
+type Handler func(msg Message)
+
+// Bus – шина событий между репликами сервера.
+//
+// Реализации:
+//   - Local: внутрипроцессная шина для одной реплики и тестов.
+//   - Postgres: шина поверх LISTEN/NOTIFY для нескольких реплик.
+type Bus interface {
+	// Publish отправляет событие всем подписчикам шины, включая подписчиков текущей реплики.
+	Publish(ctx context.Context, msg Message) error
+	// Subscribe регистрирует обработчик входящих событий.
+	Subscribe(fn Handler)
+	// Close освобождает ресурсы шины.
+	Close() error
+}
+
+// NodeID возвращает уникальный идентификатор текущей реплики: имя хоста и случайный суффикс.
+func NodeID() string {
+	host, _ := os.Hostname()
+	b := make([]byte, 4)
+	rand.Read(b)
+	return host + "-" + hex.EncodeToString(b)
+}
+
+// handlers хранит подписчиков шины и безопасен для конкурентного использования.
+type handlers struct {
+	mu   sync.RWMutex
+	list []Handler
+}
+
+func (h *handlers) add(fn Handler) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.list = append(h.list, fn)
+}
+
+func (h *handlers) dispatch(msg Message) {
+	h.mu.RLock()
+	list := h.list
+	h.mu.RUnlock()
+	for _, fn := range list {
+		fn(msg)
+	}
+}
+
+// Local – внутрипроцессная реализация Bus. События синхронно передаются всем подписчикам.
+type Local struct {
+	subs handlers
+}
+
+// NewLocal создаёт внутрипроцессную шину.
+func NewLocal() *Local {
+	return &Local{}
+}
+
+// Publish передаёт событие всем подписчикам.
+func (l *Local) Publish(_ context.Context, msg Message) error {
+	l.subs.dispatch(msg)
+	return nil
+}
+
+// Subscribe регистрирует обработчик входящих событий.
+func (l *Local) Subscribe(fn Handler) {
+	l.subs.add(fn)
+}
+
+// Close ничего не делает и нужен для соответствия интерфейсу Bus.
+func (l *Local) Close() error {
+	return nil
+}
@@ -0,0 +1,49 @@
+package bus
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+)
+
+func TestLocalFanOut(t *testing.T) {
+	l := NewLocal()
+	var mu sync.Mutex
+	got := make(map[int][]Message)
+	for i := 0; i < 3; i++ {
+		i := i
+		l.Subscribe(func(msg Message) {
+			mu.Lock()
+			defer mu.Unlock()
+			got[i] = append(got[i], msg)
+		})
+	}
+
+	msg := Message{Origin: "node-a", UserIDs: []uint{1, 2}, Payload: json.RawMessage(`{"method":"Test"}`)}
+	if err := l.Publish(context.Background(), msg); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if len(got[i]) != 1 {
+			t.Fatalf("subscriber %d got %d messages, want 1", i, len(got[i]))
+		}
+		m := got[i][0]
+		if m.Origin != msg.Origin || len(m.UserIDs) != 2 || string(m.Payload) != string(msg.Payload) {
+			t.Errorf("subscriber %d got %+v, want %+v", i, m, msg)
+		}
+	}
+}
+
+func TestLocalWithoutSubscribers(t *testing.T) {
+	if err := NewLocal().Publish(context.Background(), Message{}); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+}
+
+func TestNodeIDUnique(t *testing.T) {
+	if a, b := NodeID(), NodeID(); a == b {
+		t.Errorf("NodeID returned %q twice", a)
+	}
+}
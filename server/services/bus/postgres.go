@@ -0,0 +1,190 @@
+package bus
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	// maxNotifyPayload – ограничение PostgreSQL на размер payload у NOTIFY (8000 байт) с запасом.
+	maxNotifyPayload = 7900
+	// overflowTTL – сколько хранить крупные события в таблице bus_payloads.
+	overflowTTL = 5 * time.Minute
+	// reconnectDelay – пауза перед повторным подключением слушателя после ошибки.
+	reconnectDelay = 2 * time.Second
+)
+
+// notification – содержимое NOTIFY. Крупные события не помещаются в payload,
+// поэтому сохраняются в таблицу bus_payloads, а в уведомлении передаётся только Ref.
+type notification struct {
+	Ref int64 `json:"ref,omitempty"`
+	Message
+}
+
+// Postgres – реализация Bus поверх PostgreSQL LISTEN/NOTIFY.
+// Публикация выполняется через общий пул соединений, прослушивание – через отдельное соединение pgx,
+// которое автоматически переподключается при обрыве.
+type Postgres struct {
+	db      *sql.DB
+	dsn     string
+	channel string
+	subs    handlers
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// NewPostgres создаёт шину на канале channel и запускает слушателя.
+//
+// Параметры:
+//   - db: пул соединений для публикации событий.
+//   - dsn: строка подключения для выделенного соединения слушателя.
+//   - channel: имя канала LISTEN/NOTIFY.
+func NewPostgres(db *sql.DB, dsn, channel string) (*Postgres, error) {
+	if _, err := db.Exec(`CREATE UNLOGGED TABLE IF NOT EXISTS bus_payloads (
+		id BIGSERIAL PRIMARY KEY,
+		payload TEXT NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`); err != nil {
+		return nil, fmt.Errorf("failed to create bus_payloads: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &Postgres{
+		db:      db,
+		dsn:     dsn,
+		channel: channel,
+		cancel:  cancel,
+	}
+	p.wg.Add(2)
+	go p.listen(ctx)
+	go p.cleanupLoop(ctx)
+	return p, nil
+}
+
+// Publish отправляет событие через pg_notify. Если событие не помещается в payload NOTIFY,
+// оно сохраняется в bus_payloads, а подписчикам передаётся ссылка на запись.
+func (p *Postgres) Publish(ctx context.Context, msg Message) error {
+	data, err := json.Marshal(notification{Message: msg})
+	if err != nil {
+		return err
+	}
+
+	if len(data) > maxNotifyPayload {
+		var id int64
+		err := p.db.QueryRowContext(ctx,
+			"INSERT INTO bus_payloads (payload) VALUES ($1) RETURNING id", string(data)).Scan(&id)
+		if err != nil {
+			return fmt.Errorf("failed to store bus payload: %w", err)
+		}
+		data, _ = json.Marshal(notification{Ref: id})
+	}
+
+	if _, err := p.db.ExecContext(ctx, "SELECT pg_notify($1, $2)", p.channel, string(data)); err != nil {
+		return fmt.Errorf("failed to notify: %w", err)
+	}
+	return nil
+}
+
+// Subscribe регистрирует обработчик входящих событий.
+func (p *Postgres) Subscribe(fn Handler) {
+	p.subs.add(fn)
+}
+
+// Close останавливает слушателя и очистку bus_payloads.
+func (p *Postgres) Close() error {
+	p.cancel()
+	p.wg.Wait()
+	return nil
+}
+
+// listen держит выделенное соединение с LISTEN и переподключается при ошибках до отмены ctx.
+func (p *Postgres) listen(ctx context.Context) {
+	defer p.wg.Done()
+	for {
+		if err := p.listenOnce(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("bus: listener error: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(reconnectDelay):
+		}
+	}
+}
+
+func (p *Postgres) listenOnce(ctx context.Context) error {
+	conn, err := pgx.Connect(ctx, p.dsn)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{p.channel}.Sanitize()); err != nil {
+		return err
+	}
+	log.Printf("bus: listening on %q", p.channel)
+
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		p.handle(ctx, n.Payload)
+	}
+}
+
+// cleanupLoop периодически удаляет устаревшие события из bus_payloads, независимо от того,
+// приходят ли реплике уведомления.
+func (p *Postgres) cleanupLoop(ctx context.Context) {
+	defer p.wg.Done()
+	ticker := time.NewTicker(overflowTTL)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := p.cleanup(ctx, overflowTTL); err != nil && ctx.Err() == nil {
+				log.Printf("bus: cleanup error: %v", err)
+			}
+		}
+	}
+}
+
+// cleanup удаляет из bus_payloads события старше ttl.
+func (p *Postgres) cleanup(ctx context.Context, ttl time.Duration) error {
+	_, err := p.db.ExecContext(ctx,
+		"DELETE FROM bus_payloads WHERE created_at < now() - $1::interval",
+		fmt.Sprintf("%d milliseconds", ttl.Milliseconds()))
+	return err
+}
+
+// handle разбирает уведомление, при необходимости загружает событие из bus_payloads и передаёт его подписчикам.
+func (p *Postgres) handle(ctx context.Context, payload string) {
+	var n notification
+	if err := json.Unmarshal([]byte(payload), &n); err != nil {
+		log.Printf("bus: invalid notification: %v", err)
+		return
+	}
+
+	if n.Ref != 0 {
+		var stored string
+		err := p.db.QueryRowContext(ctx, "SELECT payload FROM bus_payloads WHERE id = $1", n.Ref).Scan(&stored)
+		if err != nil {
+			log.Printf("bus: failed to load payload %d: %v", n.Ref, err)
+			return
+		}
+		if err := json.Unmarshal([]byte(stored), &n); err != nil {
+			log.Printf("bus: invalid stored payload %d: %v", n.Ref, err)
+			return
+		}
+	}
+	p.subs.dispatch(n.Message)
+}
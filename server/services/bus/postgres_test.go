@@ -0,0 +1,100 @@
+package bus
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
+
+// newTestPostgres создаёт шину на отдельном канале. Тесты требуют PostgreSQL и пропускаются,
+// если переменная окружения DatabaseUrl не задана.
+func newTestPostgres(t *testing.T) (*Postgres, *sql.DB) {
+	t.Helper()
+	dsn := os.Getenv("DatabaseUrl")
+	if dsn == "" {
+		t.Skip("DatabaseUrl is not set")
+	}
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	p, err := NewPostgres(db, dsn, fmt.Sprintf("orion_test_%d", time.Now().UnixNano()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { p.Close() })
+	return p, db
+}
+
+// publishAndWait публикует событие и ждёт, пока оно вернётся через LISTEN.
+func publishAndWait(t *testing.T, p *Postgres, received <-chan Message, msg Message) Message {
+	t.Helper()
+	// Слушатель подключается асинхронно: повторяем публикацию, пока событие не дойдёт.
+	deadline := time.After(10 * time.Second)
+	for {
+		if err := p.Publish(context.Background(), msg); err != nil {
+			t.Fatalf("Publish: %v", err)
+		}
+		select {
+		case got := <-received:
+			return got
+		case <-time.After(500 * time.Millisecond):
+		case <-deadline:
+			t.Fatal("notification was not delivered")
+		}
+	}
+}
+
+func TestPostgresDelivery(t *testing.T) {
+	p, _ := newTestPostgres(t)
+	received := make(chan Message, 16)
+	p.Subscribe(func(msg Message) { received <- msg })
+
+	msg := Message{Origin: "node-a", UserIDs: []uint{7}, Payload: json.RawMessage(`{"method":"Test"}`)}
+	got := publishAndWait(t, p, received, msg)
+	if got.Origin != msg.Origin || string(got.Payload) != string(msg.Payload) {
+		t.Errorf("got %+v, want %+v", got, msg)
+	}
+}
+
+func TestPostgresOverflowPayload(t *testing.T) {
+	p, db := newTestPostgres(t)
+	received := make(chan Message, 16)
+	p.Subscribe(func(msg Message) { received <- msg })
+
+	text := strings.Repeat("x", 2*maxNotifyPayload)
+	payload, _ := json.Marshal(map[string]string{"message": text})
+	msg := Message{Origin: "node-a", UserIDs: []uint{7}, Payload: payload}
+
+	got := publishAndWait(t, p, received, msg)
+	if string(got.Payload) != string(payload) {
+		t.Fatalf("overflow payload was not restored: got %d bytes, want %d", len(got.Payload), len(payload))
+	}
+
+	var stored int
+	if err := db.QueryRow("SELECT COUNT(*) FROM bus_payloads WHERE payload LIKE '%' || $1 || '%'", text).Scan(&stored); err != nil {
+		t.Fatal(err)
+	}
+	if stored == 0 {
+		t.Fatal("overflow payload was not stored in bus_payloads")
+	}
+
+	if err := p.cleanup(context.Background(), 0); err != nil {
+		t.Fatalf("cleanup: %v", err)
+	}
+	if err := db.QueryRow("SELECT COUNT(*) FROM bus_payloads WHERE payload LIKE '%' || $1 || '%'", text).Scan(&stored); err != nil {
+		t.Fatal(err)
+	}
+	if stored != 0 {
+		t.Errorf("cleanup left %d expired payloads", stored)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"time"
 )
 
 var (
@@ -12,6 +13,55 @@ var (
 	UseSSL                                                                       bool
 )
 
+// Параметры шины событий между репликами сервера.
+var (
+	BusDriver  string // Реализация шины: "local" (одна реплика) или "postgres" (BUS_DRIVER).
+	BusChannel string // Канал LISTEN/NOTIFY для драйвера postgres (BUS_CHANNEL).
+)
+
+// Параметры WebSocket-соединений. Необязательные, задаются длительностями Go ("30s", "5m").
+var (
+	WSPingInterval   time.Duration // Интервал отправки ping клиенту (WS_PING_INTERVAL).
+	WSPongWait       time.Duration // Сколько ждать pong или данных от клиента (WS_PONG_WAIT).
+	WSWriteWait      time.Duration // Таймаут записи одного сообщения (WS_WRITE_WAIT).
+	WSIdleTimeout    time.Duration // Отключение при отсутствии запросов от клиента (WS_IDLE_TIMEOUT).
+	WSMaxMessageSize int64         // Максимальный размер входящего сообщения в байтах (WS_MAX_MESSAGE_SIZE).
+)
+
+// stringOr возвращает значение переменной окружения name или def, если переменная не задана.
+func stringOr(name, def string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return def
+}
+
+// durationOr возвращает длительность из переменной окружения name или def, если переменная не задана.
+func durationOr(name string, def time.Duration) time.Duration {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		log.Panicf("invalid %s: %q", name, v)
+	}
+	return d
+}
+
+// intOr возвращает число из переменной окружения name или def, если переменная не задана.
+func intOr(name string, def int64) int64 {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || n <= 0 {
+		log.Panicf("invalid %s: %q", name, v)
+	}
+	return n
+}
+
 func init() {
 	var err error
 	PortMinio, err = strconv.Atoi(os.Getenv("Minio_SERVICE_PORT"))
@@ -33,4 +83,16 @@ func init() {
 		log.Panic(err)
 	}
 	DatabaseUrl = os.Getenv("DatabaseUrl")
+
+	WSPongWait = durationOr("WS_PONG_WAIT", 60*time.Second)
+	WSPingInterval = durationOr("WS_PING_INTERVAL", WSPongWait*9/10)
+	if WSPingInterval >= WSPongWait {
+		log.Panicf("WS_PING_INTERVAL (%s) must be less than WS_PONG_WAIT (%s)", WSPingInterval, WSPongWait)
+	}
+	WSWriteWait = durationOr("WS_WRITE_WAIT", 10*time.Second)
+	WSIdleTimeout = durationOr("WS_IDLE_TIMEOUT", 30*time.Minute)
+	WSMaxMessageSize = intOr("WS_MAX_MESSAGE_SIZE", 64*1024)
+
+	BusDriver = stringOr("BUS_DRIVER", "local")
+	BusChannel = stringOr("BUS_CHANNEL", "orion_ws")
 }
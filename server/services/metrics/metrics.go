@@ -13,6 +13,10 @@ func init() {
 	prometheus.MustRegister(AppUptime)
 	prometheus.MustRegister(AppInfo)
 	prometheus.MustRegister(ActiveChatsGauge)
+	prometheus.MustRegister(WSSlowConsumersEvicted)
+	prometheus.MustRegister(WSOnlineUsersGauge)
+	prometheus.MustRegister(WSSessionDuration)
+	prometheus.MustRegister(WSDisconnects)
 }
 
 var (
@@ -30,6 +34,30 @@ var (
 		Name: "ws_manager_active_chats_total",
 		Help: "Количество активных чатов в ws manager",
 	})
+	// Количество пользователей, у которых есть хотя бы одна WebSocket-сессия.
+	WSOnlineUsersGauge = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "ws_online_users",
+		Help: "Количество пользователей с хотя бы одной активной WebSocket-сессией",
+	})
+	// Гистограмма длительности WebSocket-сессий.
+	WSSessionDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "ws_session_duration_seconds",
+		Help:    "Длительность WebSocket-сессий в секундах.",
+		Buckets: []float64{1, 10, 60, 300, 900, 3600, 4 * 3600, 24 * 3600},
+	})
+	// Счётчик отключений WebSocket-сессий с разделением по причине.
+	WSDisconnects = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ws_disconnects_total",
+			Help: "Количество отключений WebSocket-сессий с разделением по причине.",
+		},
+		[]string{"reason"},
+	)
+	// Счётчик соединений, отключённых из-за переполнения очереди исходящих сообщений.
+	WSSlowConsumersEvicted = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "ws_slow_consumers_evicted_total",
+		Help: "Количество WebSocket-соединений, отключённых из-за переполнения очереди отправки",
+	})
 	// Счётчик общего количества запросов, разделённый по методу.
 	RequestCounter = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
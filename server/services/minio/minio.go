@@ -3,10 +3,17 @@ package minio
 import (
 	"bytes"
 	"context"
+	"crypto/md5"
 	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
+	"log"
+	"net/http"
 	"orion/server/services/env"
+	"strings"
+	"sync"
 
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
@@ -28,20 +35,20 @@ func init() {
 	// Инициализация клиента.
 	client, err := minio.New(env.Endpoint, &minio.Options{Creds: credentials.NewStaticV4(env.AccessKey, env.SecretKeyMinio, ""), Secure: env.UseSSL})
 	if err != nil {
-		fmt.Errorf("failed to initialize minio client: %w", err)
+		log.Printf("failed to initialize minio client: %v", err)
 	}
 
 	// Проверка существования бакета.
 	ctx := context.Background()
 	exists, err := client.BucketExists(ctx, env.Bucket)
 	if err != nil {
-		fmt.Errorf("error checking bucket existence: %w", err)
+		log.Printf("error checking bucket existence: %v", err)
 	}
 	if !exists {
 		// Создание бакета.
 		err = client.MakeBucket(ctx, env.Bucket, minio.MakeBucketOptions{})
 		if err != nil {
-			fmt.Errorf("failed to create bucket: %w", err)
+			log.Printf("failed to create bucket: %v", err)
 		}
 	}
 
@@ -91,8 +98,110 @@ func GetPhoto(hash string) string {
 		return "none"
 	}
 
-	// Кодируем изображение в base64 и формируем data URL.
-	encodedImage := base64.StdEncoding.EncodeToString(imageBytes)
-	profilePictureURL := "data:image/jpeg;base64," + encodedImage
-	return profilePictureURL
+	return DataURL(imageBytes)
+}
+
+// DataURL кодирует изображение в data URL; тип содержимого определяется по самим данным.
+func DataURL(image []byte) string {
+	return "data:" + http.DetectContentType(image) + ";base64," + base64.StdEncoding.EncodeToString(image)
+}
+
+const (
+	// MaxImageSize – максимальный размер загружаемого изображения в байтах.
+	MaxImageSize = 5 << 20
+	// MaxDataURLSize – ограничение тела запроса с изображением в виде data URL:
+	// base64 увеличивает данные на треть, плюс запас на остальные поля запроса.
+	MaxDataURLSize = MaxImageSize*4/3 + 64<<10
+)
+
+var (
+	// ErrInvalidImage возвращается, если data URL не разобран или данные не являются изображением.
+	ErrInvalidImage = errors.New("invalid image data")
+	// ErrImageTooLarge возвращается, если изображение больше MaxImageSize.
+	ErrImageTooLarge = errors.New("image is too large")
+)
+
+// imageTypes – допустимые типы загружаемых изображений.
+var imageTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// UploadDataURL загружает изображение, переданное как data URL ("data:image/png;base64,..."),
+// и возвращает его хэш, по которому изображение затем получают через GetPhoto.
+// Размер проверяется до декодирования base64, тип – по содержимому, а не по заголовку data URL.
+//
+// Возвращаемые значения:
+//   - string: хэш изображения (имя объекта без расширения).
+//   - []byte: данные изображения.
+//   - error: ErrInvalidImage, ErrImageTooLarge или ошибка загрузки в MinIO.
+func UploadDataURL(ctx context.Context, dataURL string) (string, []byte, error) {
+	parts := strings.SplitN(dataURL, ",", 2)
+	if len(parts) != 2 {
+		return "", nil, ErrInvalidImage
+	}
+	if base64.StdEncoding.DecodedLen(len(parts[1])) > MaxImageSize+2 {
+		return "", nil, ErrImageTooLarge
+	}
+	imageBytes, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", nil, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+	if len(imageBytes) > MaxImageSize {
+		return "", nil, ErrImageTooLarge
+	}
+	contentType := http.DetectContentType(imageBytes)
+	if !imageTypes[contentType] {
+		return "", nil, ErrInvalidImage
+	}
+
+	hash := md5.Sum(imageBytes)
+	imageHex := hex.EncodeToString(hash[:])
+	if err := UploadImage(ctx, imageHex+".jpg", imageBytes, contentType); err != nil {
+		return "", nil, err
+	}
+	return imageHex, imageBytes, nil
+}
+
+// photoFetchWorkers – сколько фотографий GetPhotos загружает из MinIO одновременно.
+const photoFetchWorkers = 8
+
+// GetPhotos загружает несколько фотографий параллельно и возвращает их data URL по хэшу.
+// Повторяющиеся хэши загружаются один раз; для пустого хэша фото не запрашивается и возвращается "none".
+func GetPhotos(hashes []string) map[string]string {
+	photos := make(map[string]string, len(hashes))
+	var pending []string
+	for _, hash := range hashes {
+		if _, ok := photos[hash]; ok {
+			continue
+		}
+		photos[hash] = "none"
+		if hash != "" {
+			pending = append(pending, hash)
+		}
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	jobs := make(chan string)
+	for i := 0; i < photoFetchWorkers && i < len(pending); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for hash := range jobs {
+				photo := GetPhoto(hash)
+				mu.Lock()
+				photos[hash] = photo
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, hash := range pending {
+		jobs <- hash
+	}
+	close(jobs)
+	wg.Wait()
+	return photos
 }
@@ -0,0 +1,48 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"orion/server/services/bus"
+	"orion/server/services/metrics"
+	"time"
+)
+
+// publishTimeout – максимальное время публикации события в шину.
+const publishTimeout = 5 * time.Second
+
+// Broadcast доставляет значение v всем сессиям пользователей userIDs на всех репликах сервера.
+// Локальные сессии получают событие сразу, остальные реплики – через шину.
+func (ws *WS) Broadcast(userIDs []uint, v interface{}) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	ws.Hub.sendRawToUsers(userIDs, data)
+
+	ctx, cancel := context.WithTimeout(context.Background(), publishTimeout)
+	defer cancel()
+	if err := ws.Bus.Publish(ctx, bus.Message{Origin: ws.NodeID, UserIDs: userIDs, Payload: data}); err != nil {
+		metrics.ErrorCounter.Inc()
+		log.Printf("bus: publish failed: %v", err)
+		return err
+	}
+	return nil
+}
+
+// SendToUser доставляет значение v всем сессиям пользователя на всех репликах сервера.
+func (ws *WS) SendToUser(userID uint, v interface{}) error {
+	return ws.Broadcast([]uint{userID}, v)
+}
+
+// onBusMessage доставляет локальным сессиям событие, опубликованное другой репликой.
+func (ws *WS) onBusMessage(msg bus.Message) {
+	if msg.Origin == ws.NodeID {
+		return
+	}
+	ws.Hub.sendRawToUsers(msg.UserIDs, msg.Payload)
+}
@@ -0,0 +1,57 @@
+package ws
+
+import (
+	"log"
+	"orion/server/data/manager"
+)
+
+// Subscribe подписывает пользователя на публичный канал. Подписчик получает роль member,
+// которая в публичном канале даёт только право чтения. Возвращает число подписчиков.
+//
+// Событие ChatMembersChanged отправляется только сессиям самого пользователя,
+// чтобы не рассылать его всем подписчикам канала.
+func (ws *WS) Subscribe(userID, chatID uint) (int, error) {
+	if !manager.IsPublicChannel(chatID) {
+		return 0, ErrChatNotFound
+	}
+	added, err := manager.AddChatMembers(chatID, []uint{userID})
+	if err != nil {
+		return 0, err
+	}
+	if len(added) > 0 {
+		ws.notifySubscription(chatID, userID, added, []uint{})
+	}
+	return manager.SubscriberCount(chatID), nil
+}
+
+// Unsubscribe отписывает пользователя от публичного канала. Владелец канала отписаться не может.
+// Возвращает число подписчиков.
+func (ws *WS) Unsubscribe(userID, chatID uint) (int, error) {
+	if !manager.IsPublicChannel(chatID) {
+		return 0, ErrChatNotFound
+	}
+	if manager.IsChannelOwner(userID, chatID) {
+		return 0, ErrForbidden
+	}
+	removed, err := manager.RemoveChatMember(chatID, userID)
+	if err != nil {
+		return 0, err
+	}
+	if removed {
+		ws.notifySubscription(chatID, userID, []uint{}, []uint{userID})
+	}
+	return manager.SubscriberCount(chatID), nil
+}
+
+// notifySubscription отправляет событие ChatMembersChanged сессиям пользователя userID.
+func (ws *WS) notifySubscription(chatID, userID uint, added, removed []uint) {
+	event := Event{Method: "ChatMembersChanged", Data: map[string]interface{}{
+		"chatId":  chatID,
+		"by":      userID,
+		"added":   added,
+		"removed": removed,
+	}}
+	if err := ws.Broadcast([]uint{userID}, event); err != nil {
+		log.Printf("Failed to notify subscription to chat %d: %v", chatID, err)
+	}
+}
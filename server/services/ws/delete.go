@@ -0,0 +1,70 @@
+package ws
+
+import (
+	"log"
+	"orion/server/data/manager"
+)
+
+// DeleteMessage описывает запрос DeleteMessage – удаление сообщения.
+// При forEveryone=false сообщение скрывается только у отправителя запроса,
+// при forEveryone=true – удаляется у всех участников чата.
+//
+// Пример:
+//
+//	{ "method": "DeleteMessage", "query": { "messageId": 120, "forEveryone": true } }
+//
+// Получатели (все участники чата или только сессии самого пользователя) получают событие-«надгробие»:
+//
+//	{ "method": "MessageDeleted", "data": { "id": 120, "chatId": 1, "forEveryone": true, "deletedBy": 5 } }
+type DeleteMessage struct {
+	MessageId   uint `json:"messageId"`
+	ForEveryone bool `json:"forEveryone"`
+}
+
+// deleteMessage обрабатывает метод DeleteMessage.
+func (ws *WS) deleteMessage(req *Request, q DeleteMessage) (interface{}, error) {
+	if err := ws.DeleteMessage(req.UserID, q.MessageId, q.ForEveryone); err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"id": q.MessageId, "forEveryone": q.ForEveryone}, nil
+}
+
+// DeleteMessage удаляет сообщение от имени пользователя userID и рассылает событие MessageDeleted.
+//
+// Скрыть сообщение у себя может любой участник чата; событие получают только его собственные сессии.
+// Удалить сообщение для всех может его автор или участник с DeletionPriv;
+// событие получают все участники чата.
+func (ws *WS) DeleteMessage(userID, messageID uint, forEveryone bool) error {
+	msg, privs, err := visibleMessage(userID, messageID)
+	if err != nil {
+		return err
+	}
+
+	event := Event{Method: "MessageDeleted", Data: map[string]interface{}{
+		"id":          msg.ID,
+		"chatId":      msg.ChannelID,
+		"forEveryone": forEveryone,
+		"deletedBy":   userID,
+	}}
+
+	if !forEveryone {
+		if err := manager.HideMessage(userID, msg.ID); err != nil {
+			return err
+		}
+		if err := ws.Broadcast([]uint{userID}, event); err != nil {
+			log.Printf("Failed to broadcast hiding of message %d: %v", msg.ID, err)
+		}
+		return nil
+	}
+
+	if msg.UserID != userID && !privs.DeletionPriv {
+		return ErrForbidden
+	}
+	if err := manager.DeleteMessageForAll(msg.ID); err != nil {
+		return err
+	}
+	if err := ws.broadcastToChat(msg.ChannelID, event); err != nil {
+		log.Printf("Failed to broadcast deletion of message %d: %v", msg.ID, err)
+	}
+	return nil
+}
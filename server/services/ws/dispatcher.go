@@ -0,0 +1,88 @@
+package ws
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"orion/server/services/metrics"
+	"time"
+)
+
+var (
+	// ErrBadRequest возвращается, если сообщение клиента не удалось разобрать.
+	ErrBadRequest = errors.New("bad request")
+	// ErrUnknownMethod возвращается, если метод не зарегистрирован.
+	ErrUnknownMethod = errors.New("unknown method")
+	// ErrInvalidQuery возвращается, если query не соответствует типу запроса метода.
+	ErrInvalidQuery = errors.New("invalid query")
+)
+
+// Request описывает вызов метода WebSocket-протокола.
+//
+// Поля структуры:
+//   - ID: идентификатор запроса, переданный клиентом.
+//   - Method: имя вызываемого метода.
+//   - UserID: идентификатор пользователя, от имени которого выполняется вызов.
+//   - Client: соединение, через которое пришёл запрос.
+//   - Query: необработанное тело запроса.
+type Request struct {
+	ID     string
+	Method string
+	UserID uint
+	Client *Client
+	Query  json.RawMessage
+}
+
+// MethodFunc обрабатывает вызов метода и возвращает данные для ответа клиенту.
+type MethodFunc func(req *Request) (interface{}, error)
+
+// HandleMethod регистрирует обработчик метода WebSocket-протокола.
+// Повторная регистрация метода с тем же именем приводит к панике.
+func (ws *WS) HandleMethod(name string, fn MethodFunc) {
+	if ws.methods == nil {
+		ws.methods = make(map[string]MethodFunc)
+	}
+	if _, ok := ws.methods[name]; ok {
+		log.Panicf("ws: method %q already registered", name)
+	}
+	ws.methods[name] = fn
+}
+
+// Typed оборачивает обработчик с типизированным запросом в MethodFunc.
+// Поле query декодируется в значение типа T; ошибка декодирования возвращается клиенту как ErrInvalidQuery.
+func Typed[T any](fn func(req *Request, query T) (interface{}, error)) MethodFunc {
+	return func(req *Request) (interface{}, error) {
+		var query T
+		if len(req.Query) > 0 && string(req.Query) != "null" {
+			if err := json.Unmarshal(req.Query, &query); err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrInvalidQuery, err)
+			}
+		}
+		return fn(req, query)
+	}
+}
+
+// dispatch разбирает сообщение клиента, вызывает зарегистрированный обработчик и формирует ответ.
+func (ws *WS) dispatch(c *Client, message []byte) Response {
+	userID := c.UserID
+	var q Querys
+	if err := json.Unmarshal(message, &q); err != nil {
+		return Response{OK: false, Error: ErrBadRequest.Error()}
+	}
+
+	fn, ok := ws.methods[q.Method]
+	if !ok {
+		return Response{ID: q.ID, OK: false, Error: fmt.Sprintf("%s: %s", ErrUnknownMethod, q.Method)}
+	}
+
+	start := time.Now()
+	data, err := fn(&Request{ID: q.ID, Method: q.Method, UserID: userID, Client: c, Query: q.Query})
+	metrics.MessageProcessingTime.WithLabelValues(q.Method).Observe(time.Since(start).Seconds())
+
+	if err != nil {
+		log.Printf("ws: method %s for user %d session %s failed: %v", q.Method, userID, c.SessionID, err)
+		return Response{ID: q.ID, OK: false, Error: err.Error()}
+	}
+	return Response{ID: q.ID, OK: true, Data: data}
+}
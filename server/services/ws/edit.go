@@ -0,0 +1,87 @@
+package ws
+
+import (
+	"errors"
+	"log"
+	"orion/server/data/manager"
+	"orion/server/data/models"
+	"time"
+)
+
+var (
+	// ErrMessageNotFound возвращается, если сообщение не существует или недоступно пользователю.
+	ErrMessageNotFound = errors.New("message not found")
+	// ErrForbidden возвращается, если у пользователя нет прав на операцию.
+	ErrForbidden = errors.New("forbidden")
+)
+
+// EditMessage описывает запрос EditMessage – изменение текста сообщения.
+//
+// Пример:
+//
+//	{ "method": "EditMessage", "query": { "messageId": 120, "message": "Исправленный текст" } }
+//
+// Участники чата получают событие:
+//
+//	{ "method": "MessageEdited", "data": { "id": 120, "fromChatID": 1, "message": "...", "edited": true, ... } }
+type EditMessage struct {
+	MessageId uint   `json:"messageId"`
+	Message   string `json:"message"`
+}
+
+// editMessage обрабатывает метод EditMessage.
+func (ws *WS) editMessage(req *Request, q EditMessage) (interface{}, error) {
+	msg, err := ws.EditMessage(req.UserID, q.MessageId, q.Message)
+	if err != nil {
+		return nil, err
+	}
+	return messageEvent(msg), nil
+}
+
+// EditMessage изменяет текст сообщения от имени пользователя userID и рассылает событие MessageEdited
+// участникам чата; если текст не изменился, событие не рассылается. Редактировать сообщение может его автор (при наличии WritingPriv)
+// или участник чата с EditingPriv. Используется методом EditMessage и HTTP-обработчиком PUT /api/messages/{id}.
+func (ws *WS) EditMessage(userID, messageID uint, content string) (models.Message, error) {
+	if content == "" {
+		return models.Message{}, ErrEmptyMessage
+	}
+
+	msg, privs, err := visibleMessage(userID, messageID)
+	if err != nil {
+		return models.Message{}, err
+	}
+	// Своё сообщение можно править, пока есть право писать; чужое – только с EditingPriv.
+	// Служебные сообщения не редактируются.
+	if msg.System || (msg.UserID == userID && !privs.WritingPriv) || (msg.UserID != userID && !privs.EditingPriv) {
+		return models.Message{}, ErrForbidden
+	}
+
+	msg, changed, err := manager.EditMessage(messageID, userID, content)
+	if err != nil {
+		return models.Message{}, err
+	}
+	if !changed {
+		return msg, nil
+	}
+
+	data := messageEvent(msg)
+	data["editorId"] = userID
+	data["editedAt"] = time.Now().Format(time.RFC3339)
+	if err := ws.broadcastToChat(msg.ChannelID, Event{Method: "MessageEdited", Data: data}); err != nil {
+		log.Printf("Failed to broadcast edit of message %d: %v", msg.ID, err)
+	}
+	return msg, nil
+}
+
+// broadcastToChat рассылает событие всем участникам чата на всех репликах.
+func (ws *WS) broadcastToChat(chatID uint, event Event) error {
+	users, err := manager.GetUsersInChat(chatID)
+	if err != nil {
+		return err
+	}
+	recipients := make([]uint, 0, len(users))
+	for _, user := range users {
+		recipients = append(recipients, user.ID)
+	}
+	return ws.Broadcast(recipients, event)
+}
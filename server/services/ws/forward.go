@@ -0,0 +1,103 @@
+package ws
+
+import (
+	"fmt"
+	"log"
+	"orion/server/data/manager"
+	"orion/server/data/models"
+	"time"
+)
+
+const (
+	// maxForwardMessages – максимальное число сообщений в одной пересылке.
+	maxForwardMessages = 100
+	// maxForwardChats – максимальное число чатов назначения в одной пересылке.
+	maxForwardChats = 20
+)
+
+// ForwardMessages описывает запрос ForwardMessages – пересылку сообщений в другие чаты.
+// Сообщения копируются в каждый чат из chatIds в переданном порядке.
+//
+// Пример:
+//
+//	{ "method": "ForwardMessages", "query": { "messageIds": [118, 120], "chatIds": [3, 7] } }
+//
+// Участники каждого чата назначения получают RcvdMessage с полем forwardedFrom { userId, chatId }.
+type ForwardMessages struct {
+	MessageIds []uint `json:"messageIds"`
+	ChatIds    []uint `json:"chatIds"`
+}
+
+// forwardMessages обрабатывает метод ForwardMessages.
+func (ws *WS) forwardMessages(req *Request, q ForwardMessages) (interface{}, error) {
+	return ws.ForwardMessages(req.UserID, q.MessageIds, q.ChatIds)
+}
+
+// ForwardMessages пересылает сообщения messageIDs в чаты chatIDs от имени пользователя userID.
+//
+// Пользователь должен иметь право читать исходные чаты и писать в чаты назначения;
+// служебные сообщения (вступления, закрепления и т. п.) не пересылаются; пересылка в личный чат с пользователем, с которым есть взаимная блокировка, запрещена.
+// Все проверки выполняются до сохранения, поэтому при ошибке ничего не пересылается.
+// Возвращает подтверждения { chatId, messageId, seq, timestamp } для каждой созданной копии.
+func (ws *WS) ForwardMessages(userID uint, messageIDs, chatIDs []uint) ([]map[string]interface{}, error) {
+	if len(messageIDs) == 0 || len(chatIDs) == 0 {
+		return nil, fmt.Errorf("%w: messageIds and chatIds are required", ErrInvalidQuery)
+	}
+	if len(messageIDs) > maxForwardMessages || len(chatIDs) > maxForwardChats {
+		return nil, fmt.Errorf("%w: at most %d messages to %d chats", ErrInvalidQuery, maxForwardMessages, maxForwardChats)
+	}
+
+	originals := make([]models.Message, 0, len(messageIDs))
+	for _, id := range messageIDs {
+		msg, _, err := visibleMessage(userID, id)
+		if err != nil {
+			return nil, err
+		}
+		if msg.System {
+			return nil, fmt.Errorf("%w: system message %d cannot be forwarded", ErrInvalidQuery, id)
+		}
+		originals = append(originals, msg)
+	}
+
+	recipients := make(map[uint][]uint, len(chatIDs))
+	for _, chatID := range chatIDs {
+		if _, ok := recipients[chatID]; ok {
+			continue
+		}
+		if !manager.CanWrite(userID, chatID) {
+			return nil, ErrForbidden
+		}
+		ids, err := messageRecipients(userID, chatID)
+		if err != nil {
+			return nil, err
+		}
+		recipients[chatID] = ids
+	}
+
+	// Копии сохраняются одной транзакцией и рассылаются только после её фиксации
+	copies, err := manager.ForwardMessages(userID, chatIDs, originals)
+	if err != nil {
+		return nil, err
+	}
+
+	acks := make([]map[string]interface{}, 0, len(originals)*len(recipients))
+	for _, chatID := range chatIDs {
+		ids, ok := recipients[chatID]
+		if !ok {
+			continue
+		}
+		delete(recipients, chatID)
+		for _, stored := range copies[chatID] {
+			if err := ws.Broadcast(ids, Event{Method: "RcvdMessage", Data: messageEvent(stored)}); err != nil {
+				log.Printf("Failed to broadcast forwarded message in chat %d: %v", chatID, err)
+			}
+			acks = append(acks, map[string]interface{}{
+				"chatId":    chatID,
+				"messageId": stored.ID,
+				"seq":       stored.Seq,
+				"timestamp": stored.Timestamp.Format(time.RFC3339),
+			})
+		}
+	}
+	return acks, nil
+}
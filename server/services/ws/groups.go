@@ -0,0 +1,146 @@
+package ws
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"orion/server/data/manager"
+	"orion/server/data/models"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// maxGroupTitleLen – максимальная длина названия группы (в символах).
+	maxGroupTitleLen = 128
+	// maxGroupMembers – максимальное число участников, добавляемых одним запросом.
+	maxGroupMembers = 200
+)
+
+// CreateGroup создаёт групповой чат (public=false) или публичный канал (public=true) от имени
+// пользователя creatorID и уведомляет участников событием ChatMembersChanged.
+// Нельзя пригласить пользователя, с которым есть блокировка.
+func (ws *WS) CreateGroup(creatorID uint, title, description string, memberIDs []uint, public bool) (*models.Channel, error) {
+	title = strings.TrimSpace(title)
+	if title == "" || len([]rune(title)) > maxGroupTitleLen {
+		return nil, fmt.Errorf("%w: title must be 1-%d characters", ErrInvalidQuery, maxGroupTitleLen)
+	}
+	if len(memberIDs) > maxGroupMembers {
+		return nil, fmt.Errorf("%w: at most %d members", ErrInvalidQuery, maxGroupMembers)
+	}
+	blocked := manager.GetBlockedIDs(creatorID)
+	for _, id := range memberIDs {
+		if blocked[id] {
+			return nil, ErrBlocked
+		}
+	}
+
+	chat, err := manager.CreateGroup(creatorID, title, description, memberIDs, public)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: some members do not exist", ErrInvalidQuery)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	added := make([]uint, 0, len(chat.Users))
+	for _, u := range chat.Users {
+		added = append(added, u.ID)
+	}
+	ws.broadcastMembersChanged(chat.ID, creatorID, added, nil, nil)
+	return chat, nil
+}
+
+// AddMembers добавляет пользователей в группу от имени участника userID, у которого есть InvitingPriv.
+// Возвращает идентификаторы действительно добавленных пользователей.
+func (ws *WS) AddMembers(userID, chatID uint, memberIDs []uint) ([]uint, error) {
+	if !manager.IsChatMember(chatID, userID) {
+		return nil, ErrChatNotFound
+	}
+	if !manager.HasChannelPriv(userID, chatID, manager.PrivInviting) {
+		return nil, ErrForbidden
+	}
+	if len(memberIDs) > maxGroupMembers {
+		return nil, fmt.Errorf("%w: at most %d members", ErrInvalidQuery, maxGroupMembers)
+	}
+	blocked := manager.GetBlockedIDs(userID)
+	for _, id := range memberIDs {
+		if blocked[id] {
+			return nil, ErrBlocked
+		}
+	}
+
+	added, err := manager.AddChatMembers(chatID, memberIDs)
+	if errors.Is(err, manager.ErrNotGroup) {
+		return nil, ErrForbidden
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(added) > 0 {
+		ws.broadcastMembersChanged(chatID, userID, added, nil, nil)
+	}
+	return added, nil
+}
+
+// RemoveMember исключает пользователя memberID из группы от имени участника userID.
+// Исключить себя (выйти из группы) может любой участник, других – участник с ManagingUsersPriv.
+// Владельца исключить нельзя, и сам он выйти не может: только у него есть AdminPriv,
+// и без него управлять ролями в чате стало бы некому.
+func (ws *WS) RemoveMember(userID, chatID, memberID uint) error {
+	if !manager.IsChatMember(chatID, userID) {
+		return ErrChatNotFound
+	}
+	if manager.IsChannelOwner(memberID, chatID) ||
+		(memberID != userID && !manager.HasChannelPriv(userID, chatID, manager.PrivManagingUsers)) {
+		return ErrForbidden
+	}
+
+	removed, err := manager.RemoveChatMember(chatID, memberID)
+	if errors.Is(err, manager.ErrNotGroup) {
+		return ErrForbidden
+	}
+	if err != nil {
+		return err
+	}
+	if removed {
+		// Исключённый пользователь тоже получает событие, чтобы убрать чат из списка.
+		ws.broadcastMembersChanged(chatID, userID, nil, []uint{memberID}, []uint{memberID})
+	}
+	return nil
+}
+
+// broadcastMembersChanged рассылает событие ChatMembersChanged текущим участникам группы
+// и дополнительным получателям extra (например, исключённым пользователям).
+//
+// Пример события:
+//
+//	{ "method": "ChatMembersChanged", "data": { "chatId": 9, "by": 5, "added": [7, 8], "removed": [] } }
+func (ws *WS) broadcastMembersChanged(chatID, by uint, added, removed, extra []uint) {
+	users, err := manager.GetUsersInChat(chatID)
+	if err != nil {
+		log.Printf("Failed to load members of chat %d: %v", chatID, err)
+		return
+	}
+	recipients := append([]uint{}, extra...)
+	for _, u := range users {
+		recipients = append(recipients, u.ID)
+	}
+	if added == nil {
+		added = []uint{}
+	}
+	if removed == nil {
+		removed = []uint{}
+	}
+
+	event := Event{Method: "ChatMembersChanged", Data: map[string]interface{}{
+		"chatId":  chatID,
+		"by":      by,
+		"added":   added,
+		"removed": removed,
+	}}
+	if err := ws.Broadcast(recipients, event); err != nil {
+		log.Printf("Failed to broadcast members of chat %d: %v", chatID, err)
+	}
+}
@@ -0,0 +1,422 @@
+package ws
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"orion/server/services/metrics"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// sendBufferSize – размер очереди исходящих сообщений одного соединения.
+	sendBufferSize = 256
+	// maxHeldMessages – сколько живых событий можно отложить, пока клиенту досылаются пропущенные сообщения.
+	maxHeldMessages = 1024
+)
+
+// Причины отключения клиента, записываемые в метрику ws_disconnects_total.
+const (
+	ReasonClientClosed    = "client_closed"
+	ReasonPongTimeout     = "pong_timeout"
+	ReasonIdleTimeout     = "idle_timeout"
+	ReasonMessageTooLarge = "message_too_large"
+	ReasonSlowConsumer    = "slow_consumer"
+	ReasonWriteError      = "write_error"
+	ReasonReadError       = "read_error"
+)
+
+// Config задаёт параметры поддержания WebSocket-соединений.
+//
+// Поля структуры:
+//   - PingInterval: интервал отправки ping клиенту; должен быть меньше PongWait.
+//   - PongWait: сколько ждать pong или любых данных от клиента, прежде чем считать соединение оборванным.
+//   - WriteWait: максимальное время записи одного сообщения в сокет.
+//   - IdleTimeout: через сколько отключать клиента, не отправлявшего запросов (pong не считается активностью).
+//   - MaxMessageSize: максимальный размер входящего сообщения в байтах.
+type Config struct {
+	PingInterval   time.Duration
+	PongWait       time.Duration
+	WriteWait      time.Duration
+	IdleTimeout    time.Duration
+	MaxMessageSize int64
+}
+
+var (
+	// ErrUserOffline возвращается, если у пользователя нет активного соединения.
+	ErrUserOffline = errors.New("user is offline")
+	// ErrClientClosed возвращается при попытке отправить сообщение в закрытое соединение.
+	ErrClientClosed = errors.New("client closed")
+	// ErrSlowConsumer возвращается, если очередь исходящих сообщений соединения переполнена.
+	ErrSlowConsumer = errors.New("slow consumer")
+)
+
+// Client представляет одно WebSocket-соединение (сессию) пользователя.
+// У одного пользователя может быть несколько одновременных сессий – например, вкладки браузера и телефон.
+//
+// Все записи в сокет выполняет единственная горутина writePump, которая читает
+// буферизованную очередь send. Остальные горутины только ставят сообщения в очередь.
+//
+// Клиент может быть зарегистрирован в режиме удержания: живые события откладываются в held,
+// пока ему досылаются пропущенные сообщения, и отправляются после вызова Release.
+type Client struct {
+	UserID    uint
+	SessionID string    // Уникальный идентификатор сессии, используется в логах.
+	Connected time.Time // Время установки соединения.
+
+	hub  *Hub
+	conn *websocket.Conn
+	send chan []byte
+	done chan struct{} // Закрывается при отключении клиента.
+
+	lastActivity atomic.Int64 // Время последнего запроса клиента (UnixNano).
+
+	mu      sync.Mutex // Защищает closed, reason, holding и held.
+	closed  bool
+	reason  string // Причина отключения; фиксируется первая.
+	holding bool
+	held    [][]byte
+}
+
+// Hub владеет регистрацией WebSocket-соединений и доставкой сообщений пользователям.
+// Все методы Hub безопасны для вызова из нескольких горутин.
+type Hub struct {
+	cfg Config
+
+	mu       sync.RWMutex
+	sessions map[uint]map[string]*Client // Сессии пользователя по его ID и ID сессии.
+	count    int                         // Общее количество сессий.
+}
+
+// NewHub создаёт пустой хаб соединений с параметрами cfg.
+func NewHub(cfg Config) *Hub {
+	return &Hub{cfg: cfg, sessions: make(map[uint]map[string]*Client)}
+}
+
+// newSessionID генерирует случайный идентификатор сессии.
+func newSessionID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(b)
+}
+
+// Register создаёт новую сессию пользователя для соединения conn, регистрирует её в хабе
+// и запускает горутину записи. Существующие сессии пользователя остаются активными.
+// Для соединения устанавливаются лимит размера сообщения, дедлайн чтения и обработчик pong.
+//
+// Если hold равен true, живые события откладываются до вызова Client.Release.
+func (h *Hub) Register(userID uint, conn *websocket.Conn, hold bool) *Client {
+	c := &Client{
+		UserID:    userID,
+		SessionID: newSessionID(),
+		Connected: time.Now(),
+		hub:       h,
+		conn:      conn,
+		send:      make(chan []byte, sendBufferSize),
+		done:      make(chan struct{}),
+		holding:   hold,
+	}
+	c.touch()
+
+	conn.SetReadLimit(h.cfg.MaxMessageSize)
+	conn.SetReadDeadline(time.Now().Add(h.cfg.PongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(h.cfg.PongWait))
+	})
+
+	h.mu.Lock()
+	if h.sessions[userID] == nil {
+		h.sessions[userID] = make(map[string]*Client)
+	}
+	h.sessions[userID][c.SessionID] = c
+	h.count++
+	h.mu.Unlock()
+
+	go c.writePump()
+	return c
+}
+
+// Unregister удаляет сессию из хаба и закрывает её.
+func (h *Hub) Unregister(c *Client) {
+	h.mu.Lock()
+	if sessions, ok := h.sessions[c.UserID]; ok && sessions[c.SessionID] == c {
+		delete(sessions, c.SessionID)
+		h.count--
+		if len(sessions) == 0 {
+			delete(h.sessions, c.UserID)
+		}
+	}
+	h.mu.Unlock()
+	c.close()
+}
+
+// IsOnline сообщает, есть ли у пользователя хотя бы одна активная сессия.
+func (h *Hub) IsOnline(userID uint) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.sessions[userID]) > 0
+}
+
+// SessionCount возвращает количество активных сессий пользователя.
+func (h *Hub) SessionCount(userID uint) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.sessions[userID])
+}
+
+// Count возвращает общее количество активных сессий.
+func (h *Hub) Count() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.count
+}
+
+// UserCount возвращает количество пользователей, у которых есть хотя бы одна сессия.
+func (h *Hub) UserCount() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.sessions)
+}
+
+// OnlineUsers возвращает идентификаторы пользователей, у которых есть активное соединение.
+func (h *Hub) OnlineUsers() []uint {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	ids := make([]uint, 0, len(h.sessions))
+	for id := range h.sessions {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// SendToUser ставит значение v в очередь отправки всех сессий пользователя на текущей реплике.
+// Возвращает ErrUserOffline, если пользователь не подключён к ней.
+func (h *Hub) SendToUser(userID uint, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return h.sendRaw(userID, data)
+}
+
+// SendToUsers рассылает значение v всем перечисленным пользователям, подключённым к текущей реплике.
+// Значение сериализуется один раз. Для доставки на все реплики используйте WS.Broadcast.
+func (h *Hub) SendToUsers(userIDs []uint, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	h.sendRawToUsers(userIDs, data)
+	return nil
+}
+
+func (h *Hub) sendRawToUsers(userIDs []uint, data []byte) {
+	for _, id := range userIDs {
+		if err := h.sendRaw(id, data); err != nil && !errors.Is(err, ErrUserOffline) {
+			log.Printf("Failed to send to user %d: %v", id, err)
+		}
+	}
+}
+
+func (h *Hub) sendRaw(userID uint, data []byte) error {
+	h.mu.RLock()
+	clients := make([]*Client, 0, len(h.sessions[userID]))
+	for _, c := range h.sessions[userID] {
+		clients = append(clients, c)
+	}
+	h.mu.RUnlock()
+	if len(clients) == 0 {
+		return ErrUserOffline
+	}
+
+	delivered := 0
+	for _, c := range clients {
+		if err := c.enqueue(data); err != nil {
+			log.Printf("Failed to send to user %d session %s: %v", userID, c.SessionID, err)
+			continue
+		}
+		delivered++
+	}
+	if delivered == 0 {
+		return ErrClientClosed
+	}
+	return nil
+}
+
+// Send ставит значение v в очередь отправки клиента.
+func (c *Client) Send(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.enqueue(data)
+}
+
+// SendNow отправляет значение v в обход режима удержания, ожидая места в очереди не дольше WriteWait.
+// Используется для досылки пропущенных сообщений, объём которых может превышать размер очереди.
+func (c *Client) SendNow(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.sendBlocking(data)
+}
+
+// Release отправляет отложенные события и выключает режим удержания.
+// Порядок событий сохраняется: пока отложенные события отправляются, новые продолжают откладываться.
+func (c *Client) Release() error {
+	for {
+		c.mu.Lock()
+		batch := c.held
+		c.held = nil
+		if len(batch) == 0 {
+			c.holding = false
+			c.mu.Unlock()
+			return nil
+		}
+		c.mu.Unlock()
+
+		for _, data := range batch {
+			if err := c.sendBlocking(data); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (c *Client) sendBlocking(data []byte) error {
+	timer := time.NewTimer(c.hub.cfg.WriteWait)
+	defer timer.Stop()
+	select {
+	case c.send <- data:
+		return nil
+	case <-c.done:
+		return ErrClientClosed
+	case <-timer.C:
+		return c.evict()
+	}
+}
+
+// enqueue неблокирующе кладёт сообщение в очередь (или в held в режиме удержания).
+// Если очередь переполнена, клиент считается медленным потребителем и отключается.
+func (c *Client) enqueue(data []byte) error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return ErrClientClosed
+	}
+	if c.holding {
+		if len(c.held) >= maxHeldMessages {
+			c.mu.Unlock()
+			return c.evict()
+		}
+		c.held = append(c.held, data)
+		c.mu.Unlock()
+		return nil
+	}
+	c.mu.Unlock()
+
+	select {
+	case c.send <- data:
+		return nil
+	case <-c.done:
+		return ErrClientClosed
+	default:
+		return c.evict()
+	}
+}
+
+// evict отключает клиента, не успевающего принимать сообщения.
+func (c *Client) evict() error {
+	log.Printf("User %d session %s evicted: send queue is full", c.UserID, c.SessionID)
+	metrics.WSSlowConsumersEvicted.Inc()
+	c.SetReason(ReasonSlowConsumer)
+	c.hub.Unregister(c)
+	return ErrSlowConsumer
+}
+
+// touch отмечает активность клиента для проверки IdleTimeout.
+func (c *Client) touch() {
+	c.lastActivity.Store(time.Now().UnixNano())
+}
+
+// SetReason запоминает причину отключения клиента, если она ещё не задана.
+func (c *Client) SetReason(reason string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.reason == "" {
+		c.reason = reason
+	}
+}
+
+// Reason возвращает зафиксированную причину отключения клиента.
+func (c *Client) Reason() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.reason
+}
+
+// close помечает клиента закрытым; writePump после этого закрывает сокет.
+func (c *Client) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return
+	}
+	c.closed = true
+	c.held = nil
+	close(c.done)
+}
+
+// writePump – единственная горутина, записывающая в сокет клиента.
+// Помимо сообщений из очереди она периодически отправляет ping и отключает простаивающих клиентов.
+func (c *Client) writePump() {
+	cfg := c.hub.cfg
+	ticker := time.NewTicker(cfg.PingInterval)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+	}()
+
+	for {
+		select {
+		case <-c.done:
+			c.conn.SetWriteDeadline(time.Now().Add(cfg.WriteWait))
+			c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+			return
+		case data := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(cfg.WriteWait))
+			if err := c.conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				log.Printf("Write error for user %d session %s: %v", c.UserID, c.SessionID, err)
+				c.SetReason(ReasonWriteError)
+				c.hub.Unregister(c)
+				return
+			}
+		case <-ticker.C:
+			if time.Since(time.Unix(0, c.lastActivity.Load())) > cfg.IdleTimeout {
+				log.Printf("User %d session %s idle for more than %s", c.UserID, c.SessionID, cfg.IdleTimeout)
+				c.SetReason(ReasonIdleTimeout)
+				c.conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseGoingAway, "idle timeout"),
+					time.Now().Add(cfg.WriteWait))
+				c.hub.Unregister(c)
+				return
+			}
+			if err := c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(cfg.WriteWait)); err != nil {
+				log.Printf("Ping error for user %d session %s: %v", c.UserID, c.SessionID, err)
+				c.SetReason(ReasonWriteError)
+				c.hub.Unregister(c)
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package ws
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"orion/server/data/manager"
+	"time"
+)
+
+var (
+	// ErrChatNotFound возвращается, если чат не существует или пользователь в нём не состоит.
+	ErrChatNotFound = errors.New("chat not found")
+	// ErrBlocked возвращается, если участники чата заблокировали друг друга.
+	ErrBlocked = errors.New("user is blocked")
+	// ErrEmptyMessage возвращается при попытке отправить пустое сообщение.
+	ErrEmptyMessage = errors.New("empty message")
+	// ErrNotMember возвращается, если пользователь не состоит в чате.
+	ErrNotMember = errors.New("not a member of the chat")
+	// ErrInvalidReply возвращается, если сообщение, на которое дан ответ, не найдено в этом чате.
+	ErrInvalidReply = errors.New("reply target not found in chat")
+)
+
+// maxClientMsgIDLen – максимальная длина клиентского идентификатора сообщения (размер колонки client_msg_id).
+const maxClientMsgIDLen = 64
+
+// registerMethods регистрирует все методы WebSocket-протокола.
+func (ws *WS) registerMethods() {
+	ws.HandleMethod("RcvdMessage", Typed(ws.rcvdMessage))
+	ws.HandleMethod("GetChat", Typed(ws.getChat))
+	ws.HandleMethod("TypingStarted", Typed(ws.typingStarted))
+	ws.HandleMethod("TypingStopped", Typed(ws.typingStopped))
+	ws.HandleMethod("MarkRead", Typed(ws.markRead))
+	ws.HandleMethod("EditMessage", Typed(ws.editMessage))
+	ws.HandleMethod("DeleteMessage", Typed(ws.deleteMessage))
+	ws.HandleMethod("AddReaction", Typed(ws.addReaction))
+	ws.HandleMethod("RemoveReaction", Typed(ws.removeReaction))
+	ws.HandleMethod("ForwardMessages", Typed(ws.forwardMessages))
+	ws.HandleMethod("PinMessage", Typed(ws.pinMessage))
+	ws.HandleMethod("UnpinMessage", Typed(ws.unpinMessage))
+}
+
+// rcvdMessage сохраняет сообщение пользователя и рассылает его участникам чата.
+// Если chatId не передан (0 или -1), создаётся новый чат с пользователем user2.
+func (ws *WS) rcvdMessage(req *Request, msg RcvdMessage) (interface{}, error) {
+	if msg.Message == "" {
+		return nil, ErrEmptyMessage
+	}
+	if len(msg.ClientMsgID) > maxClientMsgIDLen {
+		return nil, fmt.Errorf("%w: clientMsgId is longer than %d", ErrInvalidQuery, maxClientMsgIDLen)
+	}
+
+	var chatID uint
+	if msg.ChatId == 0 || msg.ChatId == -1 {
+		if msg.User2 == 0 {
+			return nil, fmt.Errorf("%w: user2 not provided for new chat creation", ErrInvalidQuery)
+		}
+		newChat, err := manager.CreateChat(req.UserID, msg.User2, "")
+		if err != nil {
+			return nil, err
+		}
+		chatID = newChat.ID
+	} else if msg.ChatId > 0 {
+		chatID = uint(msg.ChatId)
+	} else {
+		return nil, fmt.Errorf("%w: chatId must be positive", ErrInvalidQuery)
+	}
+
+	recipients, err := messageRecipients(req.UserID, chatID)
+	if err != nil {
+		return nil, err
+	}
+	if msg.ReplyTo != 0 {
+		parent, err := manager.GetMessageByID(msg.ReplyTo)
+		if err != nil || parent.ChannelID != chatID {
+			return nil, ErrInvalidReply
+		}
+	}
+	// Добавляем сообщение
+	stored, duplicate, err := manager.AddMessage(req.UserID, chatID, msg.Message, msg.ClientMsgID, msg.ReplyTo)
+	if errors.Is(err, manager.ErrClientMsgIDConflict) {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidQuery, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Отправленное сообщение завершает набор текста
+	ws.stopTyping(req.UserID, chatID)
+
+	// Повтор уже был разослан участникам при первой отправке
+	if !duplicate {
+		if err := ws.Broadcast(recipients, Event{Method: "RcvdMessage", Data: messageEvent(stored)}); err != nil {
+			log.Printf("Failed to broadcast message in chat %d: %v", chatID, err)
+		}
+	}
+
+	return map[string]interface{}{
+		"chatId":      chatID,
+		"messageId":   stored.ID,
+		"seq":         stored.Seq,
+		"timestamp":   stored.Timestamp.Format(time.RFC3339),
+		"clientMsgId": msg.ClientMsgID,
+		"duplicate":   duplicate,
+	}, nil
+}
+
+// messageRecipients возвращает получателей нового сообщения пользователя senderID в чате chatID.
+// Отправитель должен иметь WritingPriv; сообщение получают только участники с ReadingPriv.
+// В личном чате блокировка запрещает отправку (ErrBlocked); в группе сообщение отправляется,
+// но не доставляется участникам, с которыми у отправителя есть блокировка.
+func messageRecipients(senderID, chatID uint) ([]uint, error) {
+	privs, err := manager.GetChannelPrivileges(chatID)
+	if err != nil {
+		return nil, err
+	}
+	sender, member := privs[senderID]
+	if !member {
+		return nil, ErrChatNotFound
+	}
+	if !sender.WritingPriv {
+		return nil, ErrForbidden
+	}
+	group := manager.GetChatByID(chatID).IsGroup
+	blocked := manager.GetBlockedIDs(senderID)
+
+	recipients := make([]uint, 0, len(privs))
+	for userID, p := range privs {
+		if userID != senderID && blocked[userID] {
+			if !group {
+				return nil, ErrBlocked
+			}
+			continue
+		}
+		if p.ReadingPriv || userID == senderID {
+			recipients = append(recipients, userID)
+		}
+	}
+	return recipients, nil
+}
+
+// getChat возвращает информацию о чате, в котором состоит пользователь.
+func (ws *WS) getChat(req *Request, q GetChat) (interface{}, error) {
+	users, err := manager.GetUsersInChat(q.ChatId)
+	if err != nil {
+		return nil, err
+	}
+
+	member := false
+	ids := make([]uint, 0, len(users))
+	for _, user := range users {
+		if user.ID == req.UserID {
+			member = true
+		}
+		ids = append(ids, user.ID)
+	}
+	online := ws.VisibleOnlineSet(req.UserID, ids)
+	userList := make([]map[string]interface{}, 0, len(users))
+	for _, user := range users {
+		userList = append(userList, map[string]interface{}{
+			"id":          user.ID,
+			"username":    user.UserName,
+			"is_online":   online[user.ID],
+			"last_online": user.LastOnline.Format(time.RFC3339),
+		})
+	}
+	if !member {
+		return nil, ErrChatNotFound
+	}
+
+	chat := manager.GetChatByID(q.ChatId)
+	return map[string]interface{}{
+		"id":           chat.ID,
+		"name":         chat.Name,
+		"description":  chat.Description,
+		"is_private":   chat.IsPrivate,
+		"is_group":     chat.IsGroup,
+		"title":        chat.Title,
+		"users":        userList,
+		"readed":       manager.IfReadedChat(chat.ID, req.UserID),
+		"unread_count": manager.GetUnreadCount(chat.ID, req.UserID),
+	}, nil
+}
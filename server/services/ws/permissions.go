@@ -0,0 +1,47 @@
+package ws
+
+import (
+	"errors"
+	"orion/server/data/manager"
+	"orion/server/data/models"
+
+	"gorm.io/gorm"
+)
+
+// visibleMessage загружает сообщение, которое пользователь userID может читать, и его привилегии в чате.
+// Если сообщения нет, пользователь не состоит в чате или не имеет ReadingPriv, возвращается ErrMessageNotFound.
+func visibleMessage(userID, messageID uint) (models.Message, manager.Privileges, error) {
+	msg, err := manager.GetMessageByID(messageID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.Message{}, manager.Privileges{}, ErrMessageNotFound
+	}
+	if err != nil {
+		return models.Message{}, manager.Privileges{}, err
+	}
+	privs := manager.GetPrivileges(userID, msg.ChannelID)
+	if !privs.ReadingPriv {
+		return models.Message{}, manager.Privileges{}, ErrMessageNotFound
+	}
+	return msg, privs, nil
+}
+
+// readableChats кэширует проверку ReadingPriv по чатам на время одной операции.
+type readableChats struct {
+	userID uint
+	cache  map[uint]bool
+}
+
+// newReadableChats создаёт кэш проверок чтения для пользователя userID.
+func newReadableChats(userID uint) *readableChats {
+	return &readableChats{userID: userID, cache: make(map[uint]bool)}
+}
+
+// allowed сообщает, может ли пользователь читать сообщения чата chatID.
+func (r *readableChats) allowed(chatID uint) bool {
+	ok, cached := r.cache[chatID]
+	if !cached {
+		ok = manager.HasChannelPriv(r.userID, chatID, manager.PrivReading)
+		r.cache[chatID] = ok
+	}
+	return ok
+}
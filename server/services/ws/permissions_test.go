@@ -0,0 +1,110 @@
+//go:build integration
+
+// Тесты работают с настоящей базой PostgreSQL (см. server/data/manager/permissions_test.go).
+package ws
+
+import (
+	"errors"
+	"fmt"
+	"orion/server/data/manager"
+	"orion/server/data/models"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	manager.Migrate()
+	os.Exit(m.Run())
+}
+
+// newTestUser создаёт пользователя с уникальными именем и почтой.
+func newTestUser(t *testing.T) uint {
+	t.Helper()
+	name := fmt.Sprintf("test_%s_%d", t.Name(), time.Now().UnixNano())
+	user := models.User{Mail: name + "@example.com", UserName: name, Password: "x", LastOnline: time.Now()}
+	if err := manager.CreateUser(&user); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	return user.ID
+}
+
+func TestVisibleMessage(t *testing.T) {
+	owner, member, outsider := newTestUser(t), newTestUser(t), newTestUser(t)
+	chat, err := manager.CreateGroup(owner, "test", "", []uint{member}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, _, err := manager.AddMessage(owner, chat.ID, "hello", "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	noRead, err := manager.CreateRole(chat.ID, "no-read", map[string]bool{manager.PrivReading: false})
+	if err != nil {
+		t.Fatal(err)
+	}
+	blind := newTestUser(t)
+	if _, err := manager.AddChatMembers(chat.ID, []uint{blind}); err != nil {
+		t.Fatal(err)
+	}
+	if err := manager.SetUserRoles(chat.ID, blind, []uint{noRead.ID}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		user    uint
+		wantErr error
+	}{
+		{"owner", owner, nil},
+		{"member", member, nil},
+		{"outsider", outsider, ErrMessageNotFound},
+		{"member without ReadingPriv", blind, ErrMessageNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, privs, err := visibleMessage(tt.user, msg.ID)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && (got.ID != msg.ID || !privs.ReadingPriv) {
+				t.Errorf("got message %d with %+v", got.ID, privs)
+			}
+		})
+	}
+
+	if _, _, err := visibleMessage(owner, msg.ID+1_000_000); !errors.Is(err, ErrMessageNotFound) {
+		t.Errorf("missing message: err = %v, want ErrMessageNotFound", err)
+	}
+}
+
+func TestReadableChats(t *testing.T) {
+	owner, subscriber := newTestUser(t), newTestUser(t)
+	public, err := manager.CreateGroup(owner, "public", "", []uint{subscriber}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	foreign, err := manager.CreateGroup(owner, "foreign", "", nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	readable := newReadableChats(subscriber)
+	if !readable.allowed(public.ID) {
+		t.Error("subscriber cannot read public channel")
+	}
+	if readable.allowed(foreign.ID) {
+		t.Error("non-member can read private group")
+	}
+
+	// Результат проверки кэшируется на время операции
+	if _, err := manager.AddChatMembers(foreign.ID, []uint{subscriber}); err != nil {
+		t.Fatal(err)
+	}
+	if readable.allowed(foreign.ID) {
+		t.Error("cached result changed within one operation")
+	}
+	if !newReadableChats(subscriber).allowed(foreign.ID) {
+		t.Error("new member cannot read the group")
+	}
+}
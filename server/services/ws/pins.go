@@ -0,0 +1,73 @@
+package ws
+
+import (
+	"log"
+	"orion/server/data/manager"
+)
+
+// PinMessage описывает запросы PinMessage и UnpinMessage.
+//
+// Пример:
+//
+//	{ "method": "PinMessage", "query": { "messageId": 120 } }
+//
+// При изменении набора закреплённых сообщений участники чата получают событие:
+//
+//	{ "method": "PinsChanged", "data": { "chatId": 1, "messageId": 120, "pinned": true,
+//	  "userId": 5, "pinnedIds": [120, 98] } }
+type PinMessage struct {
+	MessageId uint `json:"messageId"`
+}
+
+// pinMessage обрабатывает метод PinMessage.
+func (ws *WS) pinMessage(req *Request, q PinMessage) (interface{}, error) {
+	return ws.SetPinned(req.UserID, q.MessageId, true)
+}
+
+// unpinMessage обрабатывает метод UnpinMessage.
+func (ws *WS) unpinMessage(req *Request, q PinMessage) (interface{}, error) {
+	return ws.SetPinned(req.UserID, q.MessageId, false)
+}
+
+// SetPinned закрепляет (pinned=true) или открепляет сообщение от имени пользователя userID
+// и рассылает событие PinsChanged участникам чата, если набор закреплённых сообщений изменился.
+//
+// В личном чате закреплять сообщения могут оба участника, в остальных чатах – только
+// участники со статусом, у которого есть PinningMessagesPriv.
+func (ws *WS) SetPinned(userID, messageID uint, pinned bool) (map[string]interface{}, error) {
+	msg, privs, err := visibleMessage(userID, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if !privs.PinningMessagesPriv {
+		return nil, ErrForbidden
+	}
+
+	var changed bool
+	if pinned {
+		changed, err = manager.PinMessage(msg.ChannelID, msg.ID, userID)
+	} else {
+		changed, err = manager.UnpinMessage(msg.ChannelID, msg.ID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	pinnedIDs, err := manager.GetPinnedIDs(msg.ChannelID)
+	if err != nil {
+		return nil, err
+	}
+
+	data := map[string]interface{}{
+		"chatId":    msg.ChannelID,
+		"messageId": msg.ID,
+		"pinned":    pinned,
+		"userId":    userID,
+		"pinnedIds": pinnedIDs,
+	}
+	if changed {
+		if err := ws.broadcastToChat(msg.ChannelID, Event{Method: "PinsChanged", Data: data}); err != nil {
+			log.Printf("Failed to broadcast pins of chat %d: %v", msg.ChannelID, err)
+		}
+	}
+	return data, nil
+}
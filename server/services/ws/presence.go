@@ -0,0 +1,210 @@
+package ws
+
+import (
+	"log"
+	"orion/server/data/manager"
+	"sync"
+	"time"
+)
+
+const (
+	// presenceDebounce – задержка перед объявлением пользователя офлайн. Если за это время он
+	// переподключится (например, перезагрузит вкладку), событие не рассылается.
+	presenceDebounce = 5 * time.Second
+	// presenceTTL – срок актуальности записи о присутствии на другой реплике.
+	// Записи обновляются каждые 30 секунд (см. updateOnlineStatus).
+	presenceTTL = 90 * time.Second
+)
+
+// PresenceInfo описывает присутствие пользователя.
+type PresenceInfo struct {
+	UserID     uint      `json:"userId"`
+	Online     bool      `json:"online"`
+	LastOnline time.Time `json:"lastOnline"`
+}
+
+// offlineTimer – отложенное объявление пользователя офлайн.
+type offlineTimer struct {
+	timer *time.Timer
+}
+
+// presenceTracker хранит состояние, объявленное контактам текущей репликой, и отложенные уходы в офлайн.
+type presenceTracker struct {
+	mu        sync.Mutex
+	announced map[uint]bool
+	pending   map[uint]*offlineTimer
+}
+
+func newPresenceTracker() *presenceTracker {
+	return &presenceTracker{
+		announced: make(map[uint]bool),
+		pending:   make(map[uint]*offlineTimer),
+	}
+}
+
+// sessionConnected обновляет присутствие после подключения сессии и, если пользователь
+// не был объявлен онлайн, рассылает PresenceChanged его контактам.
+func (ws *WS) sessionConnected(c *Client) {
+	if err := manager.TouchPresence(c.UserID, ws.NodeID, ws.Hub.SessionCount(c.UserID)); err != nil {
+		log.Printf("Failed to store presence for user %d: %v", c.UserID, err)
+	}
+
+	p := ws.presence
+	p.mu.Lock()
+	if pending, ok := p.pending[c.UserID]; ok {
+		// Переподключение в пределах presenceDebounce – для контактов пользователь не уходил
+		pending.timer.Stop()
+		delete(p.pending, c.UserID)
+		p.mu.Unlock()
+		return
+	}
+	if p.announced[c.UserID] {
+		p.mu.Unlock()
+		return
+	}
+	p.announced[c.UserID] = true
+	p.mu.Unlock()
+
+	ws.broadcastPresence(PresenceInfo{UserID: c.UserID, Online: true, LastOnline: time.Now()})
+}
+
+// sessionDisconnected обновляет присутствие после отключения сессии. Если у пользователя
+// не осталось сессий на реплике, объявление офлайн откладывается на presenceDebounce.
+func (ws *WS) sessionDisconnected(c *Client) {
+	if n := ws.Hub.SessionCount(c.UserID); n > 0 {
+		if err := manager.TouchPresence(c.UserID, ws.NodeID, n); err != nil {
+			log.Printf("Failed to store presence for user %d: %v", c.UserID, err)
+		}
+		return
+	}
+	if err := manager.RemovePresence(c.UserID, ws.NodeID); err != nil {
+		log.Printf("Failed to remove presence for user %d: %v", c.UserID, err)
+	}
+
+	p := ws.presence
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if pending, ok := p.pending[c.UserID]; ok {
+		pending.timer.Reset(presenceDebounce)
+		return
+	}
+	pending := &offlineTimer{}
+	pending.timer = time.AfterFunc(presenceDebounce, func() { ws.announceOffline(c.UserID, pending) })
+	p.pending[c.UserID] = pending
+}
+
+// announceOffline рассылает PresenceChanged с online=false, если пользователь так и не
+// переподключился ни к одной реплике.
+func (ws *WS) announceOffline(userID uint, pending *offlineTimer) {
+	p := ws.presence
+	p.mu.Lock()
+	if p.pending[userID] != pending {
+		p.mu.Unlock()
+		return
+	}
+	delete(p.pending, userID)
+	if ws.Hub.IsOnline(userID) {
+		p.mu.Unlock()
+		return
+	}
+	delete(p.announced, userID)
+	p.mu.Unlock()
+
+	// Пользователь может оставаться онлайн на другой реплике
+	if remote, err := manager.OnlineUserIDs([]uint{userID}, ws.NodeID, presenceTTL); err == nil && len(remote) > 0 {
+		return
+	}
+	ws.broadcastPresence(PresenceInfo{UserID: userID, Online: false, LastOnline: time.Now()})
+}
+
+// broadcastPresence рассылает событие PresenceChanged контактам info.UserID (см. manager.GetContactIDs).
+//
+// Пример события:
+//
+//	{ "method": "PresenceChanged", "data": { "userId": 5, "online": false, "lastOnline": "2025-01-01T10:00:00Z" } }
+func (ws *WS) broadcastPresence(info PresenceInfo) {
+	contacts, err := manager.GetContactIDs(info.UserID)
+	if err != nil {
+		log.Printf("Failed to get contacts of user %d: %v", info.UserID, err)
+		return
+	}
+	if err := ws.Broadcast(contacts, Event{Method: "PresenceChanged", Data: info}); err != nil {
+		log.Printf("Failed to broadcast presence of user %d: %v", info.UserID, err)
+	}
+}
+
+// refreshPresence подтверждает присутствие всех пользователей, подключённых к реплике,
+// и удаляет устаревшие записи других реплик.
+func (ws *WS) refreshPresence() {
+	for _, userID := range ws.Hub.OnlineUsers() {
+		if n := ws.Hub.SessionCount(userID); n > 0 {
+			manager.TouchPresence(userID, ws.NodeID, n)
+		}
+	}
+	if err := manager.CleanupPresence(presenceTTL); err != nil {
+		log.Printf("Failed to clean up presence: %v", err)
+	}
+}
+
+// OnlineSet возвращает множество пользователей из userIDs, подключённых к любой реплике.
+func (ws *WS) OnlineSet(userIDs []uint) map[uint]bool {
+	online := make(map[uint]bool, len(userIDs))
+	var rest []uint
+	for _, id := range userIDs {
+		if ws.Hub.IsOnline(id) {
+			online[id] = true
+		} else {
+			rest = append(rest, id)
+		}
+	}
+
+	remote, err := manager.OnlineUserIDs(rest, ws.NodeID, presenceTTL)
+	if err != nil {
+		log.Printf("Failed to get remote presence: %v", err)
+	}
+	for _, id := range remote {
+		online[id] = true
+	}
+	return online
+}
+
+// IsOnline сообщает, подключён ли пользователь к любой реплике.
+func (ws *WS) IsOnline(userID uint) bool {
+	return ws.OnlineSet([]uint{userID})[userID]
+}
+
+// VisibleOnlineSet – OnlineSet с точки зрения пользователя viewerID: как и в рассылке событий
+// присутствия (см. manager.GetContactIDs), пользователи, с которыми у viewerID есть блокировка
+// в любую сторону, всегда считаются офлайн.
+func (ws *WS) VisibleOnlineSet(viewerID uint, userIDs []uint) map[uint]bool {
+	blocked := manager.GetBlockedIDs(viewerID)
+	visible := make([]uint, 0, len(userIDs))
+	for _, id := range userIDs {
+		if !blocked[id] {
+			visible = append(visible, id)
+		}
+	}
+	return ws.OnlineSet(visible)
+}
+
+// Presence возвращает присутствие перечисленных пользователей с точки зрения viewerID.
+// Для неизвестных пользователей и пользователей, с которыми у viewerID есть блокировка,
+// Online равен false, а LastOnline остаётся нулевым.
+func (ws *WS) Presence(viewerID uint, userIDs []uint) ([]PresenceInfo, error) {
+	lastOnline, err := manager.GetLastOnline(userIDs)
+	if err != nil {
+		return nil, err
+	}
+	blocked := manager.GetBlockedIDs(viewerID)
+	online := ws.VisibleOnlineSet(viewerID, userIDs)
+
+	result := make([]PresenceInfo, 0, len(userIDs))
+	for _, id := range userIDs {
+		info := PresenceInfo{UserID: id, Online: online[id]}
+		if !blocked[id] {
+			info.LastOnline = lastOnline[id]
+		}
+		result = append(result, info)
+	}
+	return result, nil
+}
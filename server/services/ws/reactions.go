@@ -0,0 +1,168 @@
+package ws
+
+import (
+	"errors"
+	"log"
+	"orion/server/data/manager"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxEmojiLen – максимальная длина эмодзи реакции в байтах (соответствует колонке reactions.emoji).
+const maxEmojiLen = 32
+
+// ErrInvalidReaction возвращается, если реакция не является одним эмодзи или слишком длинная.
+var ErrInvalidReaction = errors.New("invalid reaction")
+
+const (
+	zwj            = '\u200d' // Соединитель эмодзи в ZWJ-последовательностях (👨‍👩‍👧)
+	variationEmoji = '\ufe0f' // Селектор эмодзи-представления
+	keycap         = '\u20e3' // Рамка клавиши (1️⃣)
+	regionalFirst  = 0x1F1E6  // Региональные индикаторы, из пары которых состоит флаг
+	regionalLast   = 0x1F1FF
+	skinToneFirst  = 0x1F3FB // Модификаторы оттенка кожи
+	skinToneLast   = 0x1F3FF
+	tagFirst       = 0xE0020 // Теги флагов регионов (🏴 + теги + отмена тега)
+	tagLast        = 0xE007E
+	tagCancel      = 0xE007F
+)
+
+// pictographs – диапазоны символов, которые могут быть основой эмодзи.
+var pictographs = [][2]rune{
+	{0x00A9, 0x00A9}, {0x00AE, 0x00AE}, {0x203C, 0x203C}, {0x2049, 0x2049}, {0x2122, 0x2122},
+	{0x2139, 0x2139}, {0x2194, 0x2199}, {0x21A9, 0x21AA}, {0x231A, 0x231B}, {0x2328, 0x2328},
+	{0x23CF, 0x23CF}, {0x23E9, 0x23F3}, {0x23F8, 0x23FA}, {0x24C2, 0x24C2}, {0x25AA, 0x25AB},
+	{0x25B6, 0x25B6}, {0x25C0, 0x25C0}, {0x25FB, 0x25FE}, {0x2600, 0x27BF}, {0x2934, 0x2935},
+	{0x2B05, 0x2B07}, {0x2B1B, 0x2B1C}, {0x2B50, 0x2B50}, {0x2B55, 0x2B55}, {0x3030, 0x3030},
+	{0x303D, 0x303D}, {0x3297, 0x3297}, {0x3299, 0x3299}, {0x1F000, 0x1F1E5}, {0x1F200, 0x1F3FA},
+	{0x1F400, 0x1FAFF},
+}
+
+// isPictograph сообщает, может ли символ r быть основой эмодзи.
+func isPictograph(r rune) bool {
+	for _, rng := range pictographs {
+		if r >= rng[0] && r <= rng[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// isEmoji сообщает, является ли s ровно одним эмодзи (одной графемой): флагом из двух региональных
+// индикаторов, клавишей (1️⃣) или последовательностью пиктограмм, соединённых ZWJ, с необязательными
+// селектором представления, оттенком кожи и тегами региона.
+func isEmoji(s string) bool {
+	if s == "" || len(s) > maxEmojiLen || !utf8.ValidString(s) {
+		return false
+	}
+	runes := []rune(s)
+
+	if runes[0] >= regionalFirst && runes[0] <= regionalLast {
+		return len(runes) == 2 && runes[1] >= regionalFirst && runes[1] <= regionalLast
+	}
+	if strings.ContainsRune("0123456789#*", runes[0]) {
+		rest := runes[1:]
+		if len(rest) > 0 && rest[0] == variationEmoji {
+			rest = rest[1:]
+		}
+		return len(rest) == 1 && rest[0] == keycap
+	}
+
+	for i := 0; ; i++ {
+		// Элемент последовательности: пиктограмма [FE0F] [оттенок кожи] [теги... отмена тега]
+		if i >= len(runes) || !isPictograph(runes[i]) {
+			return false
+		}
+		i++
+		if i < len(runes) && runes[i] == variationEmoji {
+			i++
+		}
+		if i < len(runes) && runes[i] >= skinToneFirst && runes[i] <= skinToneLast {
+			i++
+		}
+		if i < len(runes) && runes[i] >= tagFirst && runes[i] <= tagLast {
+			for i < len(runes) && runes[i] >= tagFirst && runes[i] <= tagLast {
+				i++
+			}
+			if i >= len(runes) || runes[i] != tagCancel {
+				return false
+			}
+			i++
+		}
+		if i == len(runes) {
+			return true
+		}
+		if runes[i] != zwj {
+			return false
+		}
+	}
+}
+
+// Reaction описывает запросы AddReaction и RemoveReaction.
+//
+// Пример:
+//
+//	{ "method": "AddReaction", "query": { "messageId": 120, "emoji": "👍" } }
+//
+// Участники чата получают событие:
+//
+//	{ "method": "ReactionChanged", "data": { "messageId": 120, "chatId": 1, "userId": 5,
+//	  "emoji": "👍", "added": true, "count": 3 } }
+type Reaction struct {
+	MessageId uint   `json:"messageId"`
+	Emoji     string `json:"emoji"`
+}
+
+// addReaction обрабатывает метод AddReaction.
+func (ws *WS) addReaction(req *Request, q Reaction) (interface{}, error) {
+	return ws.React(req.UserID, q.MessageId, q.Emoji, true)
+}
+
+// removeReaction обрабатывает метод RemoveReaction.
+func (ws *WS) removeReaction(req *Request, q Reaction) (interface{}, error) {
+	return ws.React(req.UserID, q.MessageId, q.Emoji, false)
+}
+
+// React добавляет (add=true) или снимает реакцию пользователя userID на сообщение и рассылает
+// событие ReactionChanged участникам чата. Реакцией может быть только одно эмодзи. Ставить реакции
+// между пользователями, заблокировавшими друг друга, нельзя, но свою реакцию можно снять всегда.
+// Возвращает итоговое число реакций этим эмодзи.
+func (ws *WS) React(userID, messageID uint, emoji string, add bool) (map[string]interface{}, error) {
+	emoji = strings.TrimSpace(emoji)
+	if !isEmoji(emoji) {
+		return nil, ErrInvalidReaction
+	}
+
+	msg, _, err := visibleMessage(userID, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if add && msg.UserID != userID && manager.IsBlocked(userID, msg.UserID) {
+		return nil, ErrBlocked
+	}
+
+	var changed bool
+	if add {
+		changed, err = manager.AddReaction(msg.ID, userID, emoji)
+	} else {
+		changed, err = manager.RemoveReaction(msg.ID, userID, emoji)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	data := map[string]interface{}{
+		"messageId": msg.ID,
+		"chatId":    msg.ChannelID,
+		"userId":    userID,
+		"emoji":     emoji,
+		"added":     add,
+		"count":     manager.CountReactions(msg.ID, emoji),
+	}
+	if changed {
+		if err := ws.broadcastToChat(msg.ChannelID, Event{Method: "ReactionChanged", Data: data}); err != nil {
+			log.Printf("Failed to broadcast reaction on message %d: %v", msg.ID, err)
+		}
+	}
+	return data, nil
+}
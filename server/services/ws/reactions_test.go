@@ -0,0 +1,35 @@
+//go:build integration
+
+package ws
+
+import "testing"
+
+func TestIsEmoji(t *testing.T) {
+	tests := []struct {
+		emoji string
+		want  bool
+	}{
+		{"👍", true},
+		{"👍🏽", true},
+		{"❤️", true},
+		{"🇷🇺", true},
+		{"1️⃣", true},
+		{"👨‍👩‍👧", true},
+		{"🏳️‍🌈", true},
+		{"🏴\U000E0067\U000E0062\U000E0065\U000E006E\U000E0067\U000E007F", true},
+		{"", false},
+		{"a", false},
+		{"1", false},
+		{"ok", false},
+		{"😀😀", false},
+		{"🇷", false},
+		{"🏽", false},
+		{"👍‍", false},
+		{"‍👍", false},
+	}
+	for _, tt := range tests {
+		if got := isEmoji(tt.emoji); got != tt.want {
+			t.Errorf("isEmoji(%q) = %v, want %v", tt.emoji, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package ws
+
+import (
+	"log"
+	"orion/server/data/manager"
+)
+
+// MarkRead описывает запрос MarkRead – отметку сообщений чата прочитанными.
+// Если lastReadId не передан, прочитанными считаются все сообщения чата.
+//
+// Пример:
+//
+//	{ "method": "MarkRead", "query": { "chatId": 1, "lastReadId": 120 } }
+//
+// Участники чата (включая другие сессии прочитавшего) получают событие; в публичном канале –
+// только сессии самого прочитавшего, чтобы не раскрывать подписчикам друг друга:
+//
+//	{ "method": "MessagesRead", "data": { "chatId": 1, "readerId": 5, "lastReadId": 120 } }
+type MarkRead struct {
+	ChatId     uint `json:"chatId"`
+	LastReadId uint `json:"lastReadId"`
+}
+
+// markRead обрабатывает метод MarkRead.
+func (ws *WS) markRead(req *Request, q MarkRead) (interface{}, error) {
+	lastReadID, err := ws.MarkRead(req.UserID, q.ChatId, q.LastReadId)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"chatId": q.ChatId, "lastReadId": lastReadID}, nil
+}
+
+// MarkRead помечает сообщения чата прочитанными пользователем userID и рассылает событие MessagesRead.
+// Отмечать прочитанное может только участник с ReadingPriv. Используется и методом MarkRead, и HTTP-обработчиком /api/messages/read.
+//
+// Возвращает ID последнего прочитанного сообщения или 0, если новых прочитанных сообщений нет
+// (в этом случае событие не рассылается).
+func (ws *WS) MarkRead(userID, chatID, upToID uint) (uint, error) {
+	if !manager.IsChatMember(chatID, userID) {
+		return 0, ErrNotMember
+	}
+	if !manager.HasChannelPriv(userID, chatID, manager.PrivReading) {
+		return 0, ErrForbidden
+	}
+
+	lastReadID, err := manager.MarkMessagesRead(chatID, userID, upToID)
+	if err != nil || lastReadID == 0 {
+		return lastReadID, err
+	}
+
+	event := Event{Method: "MessagesRead", Data: map[string]interface{}{
+		"chatId":     chatID,
+		"readerId":   userID,
+		"lastReadId": lastReadID,
+	}}
+	if chat := manager.GetChatByID(chatID); chat.IsGroup && !chat.IsPrivate {
+		err = ws.SendToUser(userID, event)
+	} else {
+		err = ws.broadcastToChat(chatID, event)
+	}
+	if err != nil {
+		log.Printf("Failed to broadcast read receipt in chat %d: %v", chatID, err)
+	}
+	return lastReadID, nil
+}
@@ -1,26 +1,73 @@
 package ws
 
+import "encoding/json"
+
 // Querys представляет общий формат запроса от клиента.
 //
+// Поле id задаётся клиентом и возвращается в ответе без изменений,
+// чтобы клиент мог сопоставить ответ с запросом.
+//
 // Пример запроса:
 //
 //	{
+//	  "id": "42",
 //	  "method": "RcvdMessage",
 //	  "query": { "chatId": 1, "message": "Привет" }
 //	}
 type Querys struct {
+	ID     string          `json:"id"`
+	Method string          `json:"method"`
+	Query  json.RawMessage `json:"query"`
+}
+
+// Response представляет единый формат ответа сервера на запрос клиента.
+//
+// Пример успешного ответа:
+//
+//	{ "id": "42", "ok": true, "data": { "chatId": 1 } }
+//
+// Пример ответа с ошибкой:
+//
+//	{ "id": "42", "ok": false, "error": "unknown method" }
+type Response struct {
+	ID    string      `json:"id"`
+	OK    bool        `json:"ok"`
+	Error string      `json:"error,omitempty"`
+	Data  interface{} `json:"data,omitempty"`
+}
+
+// Event представляет событие, которое сервер отправляет клиенту по своей инициативе.
+//
+// Пример:
+//
+//	{ "method": "RcvdMessage", "data": { "fromChatID": 1, "message": "Привет" } }
+type Event struct {
 	Method string      `json:"method"`
-	Query  interface{} `json:"query"`
+	Data   interface{} `json:"data"`
 }
 
 // RcvdMessage описывает структуру запроса на отправку сообщения от клиента.
+// Если chatId равен 0 или -1, создаётся новый чат с пользователем user2.
+//
+// clientMsgId генерируется клиентом (например, UUID) и должен сохраняться при повторной отправке:
+// сервер не создаёт дубликат, а повторно возвращает подтверждение для уже сохранённого сообщения.
+//
+// replyTo – необязательный ID сообщения того же чата, на которое дан ответ.
 //
 // Пример:
 //
-//	{ "method": "RcvdMessage", "query": { "chatId": 1, "message": "Текст сообщения" } }
+//	{ "method": "RcvdMessage", "query": { "chatId": 1, "message": "Текст сообщения", "clientMsgId": "c0a8...", "replyTo": 118 } }
+//
+// Ответ (подтверждение):
+//
+//	{ "id": "42", "ok": true, "data": { "chatId": 1, "messageId": 120, "seq": 17,
+//	  "timestamp": "2025-01-01T10:00:00Z", "clientMsgId": "c0a8...", "duplicate": false } }
 type RcvdMessage struct {
-	ChatId  int    `json:"chatId"`
-	Message string `json:"message"`
+	ChatId      int    `json:"chatId"`
+	User2       uint   `json:"user2"`
+	Message     string `json:"message"`
+	ClientMsgID string `json:"clientMsgId"`
+	ReplyTo     uint   `json:"replyTo"`
 }
 
 // GetChat описывает запрос для получения информации о конкретном чате.
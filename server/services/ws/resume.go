@@ -0,0 +1,142 @@
+package ws
+
+import (
+	"net/http"
+	"orion/server/data/manager"
+	"orion/server/data/models"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxResumeMessages – максимальное число сообщений, досылаемых при переподключении.
+// Если пропущено больше, клиент получает truncated=true и должен перезагрузить историю по HTTP.
+const maxResumeMessages = 500
+
+// ResumeCursor описывает, какие сообщения клиент уже видел до переподключения.
+//
+// Передаётся в параметрах запроса на /ws:
+//   - lastMessageId: ID последнего увиденного сообщения во всех чатах;
+//   - chats: курсоры по отдельным чатам в формате "chatId:messageId,chatId:messageId".
+//
+// Пример: /service/ws?lastMessageId=120&chats=3:118,7:95
+type ResumeCursor struct {
+	LastMessageID uint
+	Chats         map[uint]uint
+}
+
+// parseResumeCursor извлекает курсоры из параметров запроса.
+// Возвращает nil, если клиент не запрашивал досылку пропущенных сообщений.
+func parseResumeCursor(r *http.Request) *ResumeCursor {
+	q := r.URL.Query()
+	cursor := &ResumeCursor{Chats: make(map[uint]uint)}
+
+	if v, err := strconv.ParseUint(q.Get("lastMessageId"), 10, 64); err == nil {
+		cursor.LastMessageID = uint(v)
+	}
+	for _, pair := range strings.Split(q.Get("chats"), ",") {
+		chatStr, msgStr, ok := strings.Cut(pair, ":")
+		if !ok {
+			continue
+		}
+		chatID, err1 := strconv.ParseUint(chatStr, 10, 64)
+		msgID, err2 := strconv.ParseUint(msgStr, 10, 64)
+		if err1 != nil || err2 != nil || chatID == 0 {
+			continue
+		}
+		cursor.Chats[uint(chatID)] = uint(msgID)
+	}
+
+	if cursor.LastMessageID == 0 && len(cursor.Chats) == 0 {
+		return nil
+	}
+	return cursor
+}
+
+// messageEvent формирует событие RcvdMessage для сохранённого сообщения.
+func messageEvent(m models.Message) map[string]interface{} {
+	data := map[string]interface{}{
+		"id":         m.ID,
+		"seq":        m.Seq,
+		"fromChatID": m.ChannelID,
+		"UserFromID": m.UserID,
+		"message":    m.Content,
+		"timestamp":  m.Timestamp.Format(time.RFC3339),
+		"edited":     m.Edited,
+	}
+	if m.System {
+		data["system"] = true
+	}
+	if m.ClientMsgID != nil {
+		data["clientMsgId"] = *m.ClientMsgID
+	}
+	if m.ReplyToID != nil {
+		data["replyTo"] = *m.ReplyToID
+	}
+	if m.ForwardedFromUserID != nil && m.ForwardedFromChatID != nil {
+		data["forwardedFrom"] = map[string]interface{}{
+			"userId": *m.ForwardedFromUserID,
+			"chatId": *m.ForwardedFromChatID,
+		}
+	}
+	return data
+}
+
+// resume досылает клиенту сообщения, пропущенные после курсора, и текущее состояние прочтения
+// его сообщений, после чего отправляет событие Resumed и включает доставку живых событий.
+//
+// Порядок событий:
+//  1. RcvdMessage с полем replay=true для каждого пропущенного сообщения;
+//  2. ReadState { chatId, lastReadId } для каждого чата, где есть прочитанные сообщения пользователя;
+//  3. Resumed { replayed, truncated, lastMessageId }.
+//
+// Живые события, пришедшие во время досылки, отправляются после Resumed; клиент должен
+// отбрасывать повторы по id сообщения.
+func (ws *WS) resume(c *Client, cursor *ResumeCursor) error {
+	msgs, err := manager.GetMessagesSince(c.UserID, cursor.LastMessageID, cursor.Chats, maxResumeMessages+1)
+	if err != nil {
+		return err
+	}
+	truncated := len(msgs) > maxResumeMessages
+	if truncated {
+		msgs = msgs[:maxResumeMessages]
+	}
+
+	lastMessageID := cursor.LastMessageID
+	readable := newReadableChats(c.UserID)
+	replayed := 0
+	for _, m := range msgs {
+		if m.ID > lastMessageID {
+			lastMessageID = m.ID
+		}
+		if !readable.allowed(m.ChannelID) {
+			continue
+		}
+		replayed++
+		data := messageEvent(m)
+		data["replay"] = true
+		if err := c.SendNow(Event{Method: "RcvdMessage", Data: data}); err != nil {
+			return err
+		}
+	}
+
+	state, err := manager.GetReadState(c.UserID)
+	if err != nil {
+		return err
+	}
+	for chatID, lastReadID := range state {
+		event := Event{Method: "ReadState", Data: map[string]interface{}{
+			"chatId":     chatID,
+			"lastReadId": lastReadID,
+		}}
+		if err := c.SendNow(event); err != nil {
+			return err
+		}
+	}
+
+	return c.SendNow(Event{Method: "Resumed", Data: map[string]interface{}{
+		"replayed":      replayed,
+		"truncated":     truncated,
+		"lastMessageId": lastMessageID,
+	}})
+}
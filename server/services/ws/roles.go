@@ -0,0 +1,169 @@
+package ws
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"orion/server/data/manager"
+	"orion/server/data/models"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// maxRoleNameLen – максимальная длина названия роли (в символах).
+const maxRoleNameLen = 64
+
+// ErrRoleNotFound возвращается, если роль не существует в указанном чате.
+var ErrRoleNotFound = errors.New("role not found")
+
+// RoleInfo – роль канала в ответах API.
+type RoleInfo struct {
+	ID         uint               `json:"id"`
+	Name       string             `json:"name"`
+	Builtin    string             `json:"builtin,omitempty"`
+	Privileges manager.Privileges `json:"privileges"`
+	Members    []uint             `json:"members"`
+}
+
+// roleInfo преобразует статус в RoleInfo.
+func roleInfo(role models.Status, members []uint) RoleInfo {
+	if members == nil {
+		members = []uint{}
+	}
+	return RoleInfo{
+		ID:      role.ID,
+		Name:    role.Name,
+		Builtin: role.Builtin,
+		Privileges: manager.Privileges{
+			EditingPriv:          role.EditingPriv,
+			DeletionPriv:         role.DeletionPriv,
+			WritingPriv:          role.WritingPriv,
+			AdminPriv:            role.AdminPriv,
+			ManagingUsersPriv:    role.ManagingUsersPriv,
+			PinningMessagesPriv:  role.PinningMessagesPriv,
+			ReadingPriv:          role.ReadingPriv,
+			InvitingPriv:         role.InvitingPriv,
+			ViewingAnalyticsPriv: role.ViewingAnalyticsPriv,
+			ChannelEditPriv:      role.ChannelEditPriv,
+		},
+		Members: members,
+	}
+}
+
+// requireAdmin проверяет, что пользователь состоит в чате и имеет AdminPriv.
+func requireAdmin(userID, chatID uint) error {
+	if !manager.IsChatMember(chatID, userID) {
+		return ErrChatNotFound
+	}
+	if !manager.HasChannelPriv(userID, chatID, manager.PrivAdmin) {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// roleError приводит ошибки слоя данных к ошибкам протокола.
+func roleError(err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return ErrRoleNotFound
+	case errors.Is(err, manager.ErrBuiltinRole):
+		return fmt.Errorf("%w: %v", ErrForbidden, err)
+	case errors.Is(err, manager.ErrUnknownPriv), errors.Is(err, manager.ErrNoRoles):
+		return fmt.Errorf("%w: %v", ErrInvalidQuery, err)
+	}
+	return err
+}
+
+// Roles возвращает роли чата с назначенными участниками. Доступно любому участнику чата.
+func (ws *WS) Roles(userID, chatID uint) ([]RoleInfo, error) {
+	if !manager.IsChatMember(chatID, userID) {
+		return nil, ErrChatNotFound
+	}
+	roles, assigned, err := manager.GetRoles(chatID)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]RoleInfo, 0, len(roles))
+	for _, role := range roles {
+		result = append(result, roleInfo(role, assigned[role.ID]))
+	}
+	return result, nil
+}
+
+// CreateRole создаёт пользовательскую роль в чате. Требуется AdminPriv.
+func (ws *WS) CreateRole(userID, chatID uint, name string, privs map[string]bool) (RoleInfo, error) {
+	if err := requireAdmin(userID, chatID); err != nil {
+		return RoleInfo{}, err
+	}
+	name = strings.TrimSpace(name)
+	if name == "" || len([]rune(name)) > maxRoleNameLen {
+		return RoleInfo{}, fmt.Errorf("%w: name must be 1-%d characters", ErrInvalidQuery, maxRoleNameLen)
+	}
+	role, err := manager.CreateRole(chatID, name, privs)
+	if err != nil {
+		return RoleInfo{}, roleError(err)
+	}
+	ws.broadcastRolesChanged(chatID, userID)
+	return roleInfo(role, nil), nil
+}
+
+// UpdateRole изменяет название (если не пустое) и привилегии роли. Требуется AdminPriv.
+func (ws *WS) UpdateRole(userID, chatID, roleID uint, name string, privs map[string]bool) (RoleInfo, error) {
+	if err := requireAdmin(userID, chatID); err != nil {
+		return RoleInfo{}, err
+	}
+	name = strings.TrimSpace(name)
+	if len([]rune(name)) > maxRoleNameLen {
+		return RoleInfo{}, fmt.Errorf("%w: name must be at most %d characters", ErrInvalidQuery, maxRoleNameLen)
+	}
+	role, err := manager.UpdateRole(chatID, roleID, name, privs)
+	if err != nil {
+		return RoleInfo{}, roleError(err)
+	}
+	ws.broadcastRolesChanged(chatID, userID)
+	return roleInfo(role, nil), nil
+}
+
+// DeleteRole удаляет пользовательскую роль. Требуется AdminPriv.
+func (ws *WS) DeleteRole(userID, chatID, roleID uint) error {
+	if err := requireAdmin(userID, chatID); err != nil {
+		return err
+	}
+	if err := manager.DeleteRole(chatID, roleID); err != nil {
+		return roleError(err)
+	}
+	ws.broadcastRolesChanged(chatID, userID)
+	return nil
+}
+
+// SetMemberRoles заменяет роли участника memberID. Требуется AdminPriv.
+func (ws *WS) SetMemberRoles(userID, chatID, memberID uint, roleIDs []uint) error {
+	if err := requireAdmin(userID, chatID); err != nil {
+		return err
+	}
+	if !manager.IsChatMember(chatID, memberID) {
+		return ErrNotMember
+	}
+	if err := manager.SetUserRoles(chatID, memberID, roleIDs); err != nil {
+		return roleError(err)
+	}
+	ws.broadcastRolesChanged(chatID, userID)
+	return nil
+}
+
+// broadcastRolesChanged уведомляет участников чата об изменении ролей, чтобы клиенты
+// перезапросили свои привилегии.
+//
+// Пример события:
+//
+//	{ "method": "RolesChanged", "data": { "chatId": 9, "by": 5 } }
+func (ws *WS) broadcastRolesChanged(chatID, by uint) {
+	event := Event{Method: "RolesChanged", Data: map[string]interface{}{
+		"chatId": chatID,
+		"by":     by,
+	}}
+	if err := ws.broadcastToChat(chatID, event); err != nil {
+		log.Printf("Failed to broadcast roles of chat %d: %v", chatID, err)
+	}
+}
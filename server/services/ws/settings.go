@@ -0,0 +1,89 @@
+package ws
+
+import (
+	"fmt"
+	"log"
+	"orion/server/data/manager"
+	"orion/server/data/models"
+	"strings"
+)
+
+// maxDescriptionLen – максимальная длина описания группы или канала (в символах).
+const maxDescriptionLen = 1024
+
+// ChannelSettings описывает изменение настроек группы или канала. Поля со значением nil не меняются.
+//
+// Avatar – хэш изображения, уже загруженного в MinIO.
+type ChannelSettings struct {
+	Title       *string
+	Description *string
+	Avatar      *string
+}
+
+// UpdateChannelSettings изменяет настройки группы или канала от имени пользователя userID,
+// у которого есть ChannelEditPriv. Изменение сопровождается служебным сообщением в чате
+// и событием ChannelUpdated для всех участников.
+//
+// Пример события:
+//
+//	{ "method": "ChannelUpdated", "data": { "chatId": 9, "by": 5, "title": "Инциденты",
+//	  "description": "...", "avatarChanged": true } }
+func (ws *WS) UpdateChannelSettings(userID, chatID uint, settings ChannelSettings) (models.Channel, error) {
+	if !manager.IsChatMember(chatID, userID) {
+		return models.Channel{}, ErrChatNotFound
+	}
+	if !manager.HasChannelPriv(userID, chatID, manager.PrivChannelEdit) {
+		return models.Channel{}, ErrForbidden
+	}
+	if settings.Title != nil {
+		title := strings.TrimSpace(*settings.Title)
+		if title == "" || len([]rune(title)) > maxGroupTitleLen {
+			return models.Channel{}, fmt.Errorf("%w: title must be 1-%d characters", ErrInvalidQuery, maxGroupTitleLen)
+		}
+		settings.Title = &title
+	}
+	if settings.Description != nil && len([]rune(*settings.Description)) > maxDescriptionLen {
+		return models.Channel{}, fmt.Errorf("%w: description must be at most %d characters", ErrInvalidQuery, maxDescriptionLen)
+	}
+
+	before := manager.GetChatByID(chatID)
+	chat, err := manager.UpdateChannelSettings(chatID, settings.Title, settings.Description, settings.Avatar)
+	if err != nil {
+		return models.Channel{}, err
+	}
+
+	// Служебное сообщение описывает только реально изменившиеся поля
+	var changes []string
+	if chat.Title != before.Title {
+		changes = append(changes, fmt.Sprintf("название на «%s»", chat.Title))
+	}
+	if chat.Description != before.Description {
+		changes = append(changes, "описание")
+	}
+	if chat.Avatar != before.Avatar {
+		changes = append(changes, "аватар")
+	}
+	if len(changes) == 0 {
+		return chat, nil
+	}
+
+	user := manager.GetUserByID(userID)
+	content := fmt.Sprintf("%s изменил(а) %s", user.UserName, strings.Join(changes, ", "))
+	if msg, err := manager.AddSystemMessage(chatID, userID, content); err != nil {
+		log.Printf("Failed to add system message to chat %d: %v", chatID, err)
+	} else if err := ws.broadcastToChat(chatID, Event{Method: "RcvdMessage", Data: messageEvent(msg)}); err != nil {
+		log.Printf("Failed to broadcast system message in chat %d: %v", chatID, err)
+	}
+
+	event := Event{Method: "ChannelUpdated", Data: map[string]interface{}{
+		"chatId":        chatID,
+		"by":            userID,
+		"title":         chat.Title,
+		"description":   chat.Description,
+		"avatarChanged": chat.Avatar != before.Avatar,
+	}}
+	if err := ws.broadcastToChat(chatID, event); err != nil {
+		log.Printf("Failed to broadcast settings of chat %d: %v", chatID, err)
+	}
+	return chat, nil
+}
@@ -0,0 +1,195 @@
+package ws
+
+import (
+	"log"
+	"orion/server/data/manager"
+	"sync"
+	"time"
+)
+
+const (
+	// typingTTL – через сколько индикатор набора гаснет, если клиент не прислал TypingStarted повторно.
+	typingTTL = 6 * time.Second
+	// typingRateInterval – не чаще этого интервала TypingStarted одного пользователя рассылается в чат.
+	typingRateInterval = 2 * time.Second
+)
+
+// Typing описывает запросы TypingStarted и TypingStopped.
+//
+// Пример:
+//
+//	{ "method": "TypingStarted", "query": { "chatId": 1 } }
+//
+// Остальные участники чата получают событие:
+//
+//	{ "method": "TypingStarted", "data": { "chatId": 1, "userId": 5, "expiresIn": 6 } }
+//
+// Клиенту следует повторять TypingStarted, пока пользователь продолжает набор: индикатор гаснет
+// сам через expiresIn секунд после последнего запроса.
+type Typing struct {
+	ChatId uint `json:"chatId"`
+}
+
+type typingKey struct {
+	userID uint
+	chatID uint
+}
+
+type typingEntry struct {
+	sessionID     string
+	lastBroadcast time.Time
+	timer         *time.Timer
+}
+
+// typingTracker хранит активные индикаторы набора текущей реплики и гасит их по истечении typingTTL.
+type typingTracker struct {
+	mu     sync.Mutex
+	active map[typingKey]*typingEntry
+}
+
+func newTypingTracker() *typingTracker {
+	return &typingTracker{active: make(map[typingKey]*typingEntry)}
+}
+
+// typingStarted продлевает индикатор набора и, если с прошлой рассылки прошло не меньше
+// typingRateInterval, сообщает о нём остальным участникам чата.
+func (ws *WS) typingStarted(req *Request, q Typing) (interface{}, error) {
+	key := typingKey{userID: req.UserID, chatID: q.ChatId}
+	t := ws.typing
+
+	t.mu.Lock()
+	entry, ok := t.active[key]
+	if ok && time.Since(entry.lastBroadcast) < typingRateInterval {
+		entry.timer.Reset(typingTTL)
+		t.mu.Unlock()
+		return map[string]interface{}{"throttled": true}, nil
+	}
+	t.mu.Unlock()
+
+	recipients, err := typingRecipients(req.UserID, q.ChatId)
+	if err != nil {
+		return nil, err
+	}
+	// Подписчики публичного канала без права писать не набирают текст
+	if !manager.CanWrite(req.UserID, q.ChatId) {
+		return nil, ErrForbidden
+	}
+
+	t.mu.Lock()
+	entry, ok = t.active[key]
+	if !ok {
+		entry = &typingEntry{}
+		entry.timer = time.AfterFunc(typingTTL, func() { ws.expireTyping(key, entry) })
+		t.active[key] = entry
+	} else {
+		entry.timer.Reset(typingTTL)
+	}
+	entry.sessionID = req.Client.SessionID
+	entry.lastBroadcast = time.Now()
+	t.mu.Unlock()
+
+	event := Event{Method: "TypingStarted", Data: map[string]interface{}{
+		"chatId":    q.ChatId,
+		"userId":    req.UserID,
+		"expiresIn": int(typingTTL / time.Second),
+	}}
+	if err := ws.Broadcast(recipients, event); err != nil {
+		log.Printf("Failed to broadcast typing in chat %d: %v", q.ChatId, err)
+	}
+	return map[string]interface{}{"throttled": false}, nil
+}
+
+// typingStopped гасит индикатор набора пользователя в чате.
+func (ws *WS) typingStopped(req *Request, q Typing) (interface{}, error) {
+	ws.stopTyping(req.UserID, q.ChatId)
+	return nil, nil
+}
+
+// stopTyping гасит индикатор набора, если он активен, и сообщает об этом участникам чата.
+func (ws *WS) stopTyping(userID, chatID uint) {
+	key := typingKey{userID: userID, chatID: chatID}
+	ws.typing.mu.Lock()
+	entry, ok := ws.typing.active[key]
+	if ok {
+		entry.timer.Stop()
+		delete(ws.typing.active, key)
+	}
+	ws.typing.mu.Unlock()
+
+	if ok {
+		ws.broadcastTypingStopped(key)
+	}
+}
+
+// stopSessionTyping гасит все индикаторы, запущенные из указанной сессии (например, при её отключении).
+func (ws *WS) stopSessionTyping(c *Client) {
+	var stopped []typingKey
+	ws.typing.mu.Lock()
+	for key, entry := range ws.typing.active {
+		if key.userID == c.UserID && entry.sessionID == c.SessionID {
+			entry.timer.Stop()
+			delete(ws.typing.active, key)
+			stopped = append(stopped, key)
+		}
+	}
+	ws.typing.mu.Unlock()
+
+	for _, key := range stopped {
+		ws.broadcastTypingStopped(key)
+	}
+}
+
+// expireTyping вызывается таймером, когда клиент перестал продлевать индикатор.
+func (ws *WS) expireTyping(key typingKey, entry *typingEntry) {
+	ws.typing.mu.Lock()
+	current, ok := ws.typing.active[key]
+	if !ok || current != entry {
+		ws.typing.mu.Unlock()
+		return
+	}
+	delete(ws.typing.active, key)
+	ws.typing.mu.Unlock()
+
+	ws.broadcastTypingStopped(key)
+}
+
+func (ws *WS) broadcastTypingStopped(key typingKey) {
+	recipients, err := typingRecipients(key.userID, key.chatID)
+	if err != nil {
+		return
+	}
+	event := Event{Method: "TypingStopped", Data: map[string]interface{}{
+		"chatId": key.chatID,
+		"userId": key.userID,
+	}}
+	if err := ws.Broadcast(recipients, event); err != nil {
+		log.Printf("Failed to broadcast typing stop in chat %d: %v", key.chatID, err)
+	}
+}
+
+// typingRecipients возвращает участников чата, которым нужно показать индикатор набора userID:
+// всех, кроме самого пользователя и тех, с кем у него взаимная блокировка.
+func typingRecipients(userID, chatID uint) ([]uint, error) {
+	users, err := manager.GetUsersInChat(chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	member := false
+	blocked := manager.GetBlockedIDs(userID)
+	recipients := make([]uint, 0, len(users))
+	for _, user := range users {
+		if user.ID == userID {
+			member = true
+			continue
+		}
+		if blocked[user.ID] {
+			continue
+		}
+		recipients = append(recipients, user.ID)
+	}
+	if !member {
+		return nil, ErrNotMember
+	}
+	return recipients, nil
+}
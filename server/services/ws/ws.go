@@ -1,10 +1,13 @@
 package ws
 
 import (
-	"encoding/json"
+	"errors"
 	"log"
+	"net"
 	"net/http"
 	"orion/server/data/manager"
+	"orion/server/services/bus"
+	"orion/server/services/env"
 	"orion/server/services/jwt"
 	"orion/server/services/metrics"
 	"time"
@@ -14,8 +17,14 @@ import (
 
 // WS представляет менеджер WebSocket-соединений.
 type WS struct {
-	Upgrader    websocket.Upgrader
-	Connections map[uint]*websocket.Conn // Соответствие между ID пользователя и его WebSocket-соединением.
+	Upgrader websocket.Upgrader
+	Hub      *Hub    // Реестр активных соединений текущей реплики.
+	Bus      bus.Bus // Шина для доставки событий пользователям, подключённым к другим репликам.
+	NodeID   string  // Идентификатор текущей реплики в шине.
+
+	methods  map[string]MethodFunc // Зарегистрированные методы протокола.
+	typing   *typingTracker        // Активные индикаторы набора текста.
+	presence *presenceTracker      // Объявленное контактам присутствие пользователей.
 }
 
 // WSmanager – глобальный экземпляр менеджера WebSocket-соединений.
@@ -24,7 +33,8 @@ var WSmanager = WS{}
 func SendCountConn() {
 	ticker := time.NewTicker(time.Second * 5)
 	for _ = range ticker.C {
-		metrics.ActiveChatsGauge.Set(float64(len(WSmanager.Connections)))
+		metrics.ActiveChatsGauge.Set(float64(WSmanager.Hub.Count()))
+		metrics.WSOnlineUsersGauge.Set(float64(WSmanager.Hub.UserCount()))
 	}
 }
 
@@ -33,13 +43,15 @@ func updateOnlineStatus() {
 	defer ticker.Stop()
 
 	for range ticker.C {
-		for userID := range WSmanager.Connections {
+		for _, userID := range WSmanager.Hub.OnlineUsers() {
 			manager.UpdateLastOnline(userID, time.Now())
 		}
+		WSmanager.refreshPresence()
 	}
 }
 
-// init инициализирует менеджер WebSocket: устанавливает апгрейдер и инициализирует карту подключений.
+// init инициализирует менеджер WebSocket: устанавливает апгрейдер, создаёт хаб соединений,
+// подключается к шине событий и регистрирует методы протокола.
 func init() {
 	WSmanager.Upgrader = websocket.Upgrader{
 		// Разрешает подключение с любого источника; для продакшена рекомендуется ограничить список допустимых.
@@ -47,15 +59,49 @@ func init() {
 			return true
 		},
 	}
-	WSmanager.Connections = make(map[uint]*websocket.Conn)
+	WSmanager.Hub = NewHub(Config{
+		PingInterval:   env.WSPingInterval,
+		PongWait:       env.WSPongWait,
+		WriteWait:      env.WSWriteWait,
+		IdleTimeout:    env.WSIdleTimeout,
+		MaxMessageSize: env.WSMaxMessageSize,
+	})
+	WSmanager.NodeID = bus.NodeID()
+	WSmanager.Bus = newBus()
+	WSmanager.Bus.Subscribe(WSmanager.onBusMessage)
+	WSmanager.typing = newTypingTracker()
+	WSmanager.presence = newPresenceTracker()
+	WSmanager.registerMethods()
 	go SendCountConn()
 	go updateOnlineStatus()
 
 }
 
+// newBus создаёт шину событий согласно env.BusDriver.
+func newBus() bus.Bus {
+	switch env.BusDriver {
+	case "postgres":
+		sqlDB, err := manager.DB.DB()
+		if err != nil {
+			log.Fatalf("bus: failed to get database handle: %v", err)
+		}
+		b, err := bus.NewPostgres(sqlDB, env.DatabaseUrl, env.BusChannel)
+		if err != nil {
+			log.Fatalf("bus: %v", err)
+		}
+		return b
+	case "local":
+		return bus.NewLocal()
+	default:
+		log.Fatalf("bus: unknown driver %q", env.BusDriver)
+		return nil
+	}
+}
+
 // HandleWebSocket обрабатывает установку WebSocket-соединения и входящие сообщения от клиента.
-// Функция извлекает JWT-токен для идентификации пользователя, обновляет карту соединений и
-// выполняет обработку поступающих запросов.
+// Функция извлекает JWT-токен для идентификации пользователя, регистрирует соединение в хабе,
+// при наличии курсоров (см. ResumeCursor) досылает пропущенные сообщения и передаёт поступающие
+// запросы диспетчеру методов; результат каждого вызова возвращается клиенту в виде Response.
 func (ws *WS) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
 	userID, err := jwt.ExtractJWT(w, r)
 	if err != nil {
@@ -69,125 +115,62 @@ func (ws *WS) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	manager.UpdateLastOnline(userID, time.Now())
-	ws.Connections[userID] = conn
-	log.Printf("User %d connected", userID)
+	cursor := parseResumeCursor(r)
+	client := ws.Hub.Register(userID, conn, cursor != nil)
+	log.Printf("User %d session %s connected (%d active)", userID, client.SessionID, ws.Hub.SessionCount(userID))
+	ws.sessionConnected(client)
 
 	defer func() {
-		conn.Close()
-		delete(ws.Connections, userID)
-		manager.UpdateLastOnline(userID, time.Now())
-		log.Printf("User %d disconnected", userID)
+		ws.Hub.Unregister(client)
+		ws.stopSessionTyping(client)
+		ws.sessionDisconnected(client)
+		metrics.WSSessionDuration.Observe(time.Since(client.Connected).Seconds())
+		metrics.WSDisconnects.WithLabelValues(client.Reason()).Inc()
+		log.Printf("User %d session %s disconnected: %s", userID, client.SessionID, client.Reason())
 
 		// Обновляем LastOnline при отключении
 		manager.UpdateLastOnline(userID, time.Now())
 	}()
 
-	for {
-		_, message, err := conn.ReadMessage()
-		if err != nil {
-			log.Println("Read error:", err)
-			break
-		}
-
-		// Обработка сообщения
-		var dt Querys
-		if err := json.Unmarshal(message, &dt); err != nil {
-			log.Println("Unmarshal error:", err)
-			continue
-		}
-		log.Println("Received request:", dt)
-
-		startTime := time.Now()
-		defer func(method string, start time.Time) {
-			elapsed := time.Since(start)
-			metrics.MessageProcessingTime.WithLabelValues(method).Observe(elapsed.Seconds())
-		}(dt.Method, startTime)
-
-		if dt.Method != "RcvdMessage" {
-			log.Println("Unsupported method:", dt.Method)
-			continue
-		}
-		// Добавьте проверку блокировки
-
-		dat, ok := dt.Query.(map[string]interface{})
-		if !ok {
-			log.Println("Invalid query format for RcvdMessage")
-			continue
+	if cursor != nil {
+		if err := ws.resume(client, cursor); err != nil {
+			log.Printf("Resume failed for user %d session %s: %v", userID, client.SessionID, err)
+			client.SendNow(Event{Method: "Resumed", Data: map[string]interface{}{"truncated": true, "error": err.Error()}})
 		}
-
-		// Безопасное извлечение и проверка chatId
-		var chatId int
-		if chatRaw, ok := dat["chatId"]; ok {
-			if chatFloat, ok := chatRaw.(float64); ok && chatFloat > 0 {
-				chatId = int(chatFloat)
-			}
+		if err := client.Release(); err != nil {
+			log.Printf("Failed to release user %d session %s: %v", userID, client.SessionID, err)
+			return
 		}
+	}
 
-		messageText, _ := dat["message"].(string)
-		msg := RcvdMessage{ChatId: chatId, Message: messageText}
-		var NewChatId uint
-		// Если chatId не передан — создаём новый чат
-		if chatId == 0 || chatId == -1 {
-
-			user2Raw, ok := dat["user2"]
-			if !ok {
-				log.Println("user2 not provided for new chat creation")
-				continue
-			}
-			user2ID := uint(user2Raw.(float64))
-
-			newChat, err := manager.CreateChat(userID, user2ID, "еые")
-			if err != nil {
-				log.Println("CreateChat error:", err)
-				continue
-			}
-			NewChatId = newChat.ID
-			msg.ChatId = chatId
-		} else {
-			NewChatId = uint(chatId)
-		}
-		// Добавьте проверку блокировки
-		users, err := manager.GetUsersInChat(NewChatId)
+	for {
+		_, message, err := conn.ReadMessage()
 		if err != nil {
-			log.Println("GetUsersInChat error:", err)
-			continue
-		}
-
-		var otherUserID uint
-		for _, user := range users {
-			if user.ID != userID {
-				otherUserID = user.ID
-				break
-			}
+			client.SetReason(disconnectReason(err))
+			log.Printf("Read error for user %d session %s: %v", userID, client.SessionID, err)
+			break
 		}
+		client.touch()
 
-		if manager.IsBlocked(userID, otherUserID) {
-			log.Printf("Message blocked: users %d and %d are blocked", userID, otherUserID)
-			continue
-		}
-		// Добавляем сообщение
-		manager.AddMessage(userID, NewChatId, msg.Message)
-
-		// Формируем ответ
-		resp := map[string]interface{}{
-			"method": "RcvdMessage",
-			"data": map[string]interface{}{
-				"fromChatID": chatId,
-				"UserFromID": userID,
-				"message":    msg.Message,
-				"timestamp":  time.Now().Format(time.RFC3339),
-				"Readed":     false,
-			},
+		resp := ws.dispatch(client, message)
+		if err := client.Send(resp); err != nil {
+			log.Printf("Failed to reply to user %d session %s: %v", userID, client.SessionID, err)
+			break
 		}
+	}
+}
 
-		for _, user := range users {
-			if c, ok := ws.Connections[user.ID]; ok {
-				if err := c.WriteJSON(resp); err != nil {
-					log.Printf("Failed to send to user %d: %v", user.ID, err)
-					c.Close()
-					delete(ws.Connections, user.ID)
-				}
-			}
-		}
+// disconnectReason определяет причину отключения по ошибке чтения из сокета.
+func disconnectReason(err error) string {
+	var netErr net.Error
+	switch {
+	case websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway, websocket.CloseNoStatusReceived):
+		return ReasonClientClosed
+	case errors.Is(err, websocket.ErrReadLimit):
+		return ReasonMessageTooLarge
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return ReasonPongTimeout
+	default:
+		return ReasonReadError
 	}
 }